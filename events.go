@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"timer_tui/internal"
+	"timer_tui/internal/config"
+	"timer_tui/internal/hooks"
+	"timer_tui/internal/idle"
+	"timer_tui/internal/project"
+	"timer_tui/internal/webhook"
+)
+
+// loadEvents sets up the hooks and webhooks configured in the config
+// directory. Webhook deliveries are queued through store.
+func loadEvents(store project.Store) (internal.Events, error) {
+	var ev internal.Events
+
+	hooksPath, err := config.HooksPath()
+	if err != nil {
+		return ev, err
+	}
+	if ev.Hooks, err = hooks.Load(hooksPath); err != nil {
+		return ev, fmt.Errorf("failed to load hooks: %w", err)
+	}
+
+	webhooksPath, err := config.WebhooksPath()
+	if err != nil {
+		return ev, err
+	}
+	cfg, err := webhook.LoadConfig(webhooksPath)
+	if err != nil {
+		return ev, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	if cfg != nil {
+		ev.Webhooks = webhook.New(store, *cfg, nil)
+	}
+	return ev, nil
+}
+
+// loadIdle reads the idle detection settings from the config directory. It
+// returns nil if detection is not set up.
+func loadIdle() (*idle.Config, error) {
+	path, err := config.IdlePath()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := idle.LoadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load idle settings: %w", err)
+	}
+	return cfg, nil
+}
+
+// detachWebhooks returns a function that starts "timer_tui webhooks" on the
+// database in the background, without waiting for it.
+func detachWebhooks(dbPath string) func() error {
+	return func() error {
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		cmd := exec.Command(exe, "--db", dbPath, "webhooks")
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("start webhook delivery: %w", err)
+		}
+		return cmd.Process.Release()
+	}
+}
@@ -0,0 +1,619 @@
+package internal
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"timer_tui/internal/hooks"
+	"timer_tui/internal/project"
+	"timer_tui/internal/timelog"
+	"timer_tui/internal/tracker"
+)
+
+// commands are the headless subcommands. They apply the same start/stop
+// rules as the TUI through the tracker package.
+var commands = map[string]func(store project.Store, ev Events, args []string, out io.Writer) error{
+	"start":    runStart,
+	"stop":     runStop,
+	"tag":      runTag,
+	"add":      runAdd,
+	"status":   runStatus,
+	"list":     runList,
+	"log":      runLog,
+	"report":   runReport,
+	"tags":     runTags,
+	"webhooks": runWebhooks,
+	"serve":    runServe,
+}
+
+// IsCommand reports whether name is a headless subcommand.
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+// RunCommand runs the named headless subcommand against store, then waits
+// for the events it sent to be handled.
+func RunCommand(store project.Store, ev Events, name string, args []string, out io.Writer) error {
+	run, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q", name)
+	}
+	err := run(store, ev, args, out)
+	if werr := ev.Wait(); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// parseArgs parses flags wherever they appear among the positional
+// arguments, so `start my project --tag x` works as well as the flag-first
+// form the flag package expects.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// findProject looks a project up by ID or by case-insensitive name.
+func findProject(store project.Store, ref string) (*project.Project, error) {
+	projects, err := store.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return matchProject(projects, ref)
+}
+
+func matchProject(projects []project.Project, ref string) (*project.Project, error) {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		for i := range projects {
+			if projects[i].ID == id {
+				return &projects[i], nil
+			}
+		}
+	}
+
+	var match *project.Project
+	for i := range projects {
+		if strings.EqualFold(projects[i].Name, ref) {
+			if match != nil {
+				return nil, fmt.Errorf("more than one project is named %q; use its ID", ref)
+			}
+			match = &projects[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("no project named %q", ref)
+	}
+	return match, nil
+}
+
+// parseStartArgs returns the project reference and tag given to start.
+func parseStartArgs(args []string) (ref, tag string, err error) {
+	fs := flag.NewFlagSet("start", flag.ContinueOnError)
+	fs.StringVar(&tag, "tag", "", "tag to log the session with if none is given on stop")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return "", "", err
+	}
+	if len(positional) == 0 {
+		return "", "", errors.New("usage: timer_tui start <project> [--tag TAG]")
+	}
+	return strings.Join(positional, " "), tag, nil
+}
+
+func runStart(store project.Store, ev Events, args []string, out io.Writer) error {
+	ref, tag, err := parseStartArgs(args)
+	if err != nil {
+		return err
+	}
+
+	p, err := findProject(store, ref)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var stopped []tracker.Stopped
+	var events []hooks.Payload
+	err = store.WithTx(func(tx project.Store) error {
+		var started *project.Project
+		var err error
+		if started, stopped, err = tracker.Start(tx, p.ID, now, tag); err != nil {
+			return err
+		}
+		payload := hooks.ProjectEvent(hooks.Start, started, now)
+		payload.Tag = tag
+		events = append(stoppedEvents(stopped, now), payload)
+		return ev.queue(tx, events)
+	})
+	if errors.Is(err, tracker.ErrAlreadyRunning) {
+		return fmt.Errorf("%s is already running", p.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, s := range stopped {
+		fmt.Fprintf(out, "Stopped %s (%s)\n", s.Project.Name, formatDuration(loggedDuration(s.Logs)))
+	}
+	fmt.Fprintf(out, "Started %s\n", p.Name)
+	ev.fire(events...)
+	return nil
+}
+
+// parseStopArgs returns the tag given to stop.
+func parseStopArgs(args []string) (tag string, err error) {
+	fs := flag.NewFlagSet("stop", flag.ContinueOnError)
+	fs.StringVar(&tag, "tag", "", "tag for the logged session")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return "", err
+	}
+	if len(positional) > 0 {
+		return "", errors.New("usage: timer_tui stop [--tag TAG]")
+	}
+	return tag, nil
+}
+
+func runStop(store project.Store, ev Events, args []string, out io.Writer) error {
+	tag, err := parseStopArgs(args)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var stopped []tracker.Stopped
+	err = store.WithTx(func(tx project.Store) error {
+		projects, err := tx.GetAll()
+		if err != nil {
+			return err
+		}
+		for _, p := range projects {
+			if !p.Running {
+				continue
+			}
+			s, err := tracker.Stop(tx, p.ID, now, tag)
+			if err != nil {
+				return err
+			}
+			stopped = append(stopped, s)
+		}
+		return ev.queue(tx, stoppedEvents(stopped, now))
+	})
+	if err != nil {
+		return err
+	}
+	if len(stopped) == 0 {
+		return errors.New("no timer is running")
+	}
+
+	writeStopped(out, stopped)
+	ev.fire(stoppedEvents(stopped, now)...)
+	return nil
+}
+
+func writeStopped(out io.Writer, stopped []tracker.Stopped) {
+	for _, s := range stopped {
+		tag := ""
+		if n := len(s.Logs); n > 0 && len(s.Logs[n-1].Tags) > 0 {
+			tag = " [" + timelog.FormatTags(s.Logs[n-1].Tags) + "]"
+		}
+		fmt.Fprintf(out, "Stopped %s (%s)%s\n", s.Project.Name, formatDuration(loggedDuration(s.Logs)), tag)
+	}
+}
+
+func parseTagArgs(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errors.New("usage: timer_tui tag <tag>")
+	}
+	return strings.Join(args, " "), nil
+}
+
+// runTag sets the tag the running session is logged with if it is stopped
+// untagged.
+func runTag(store project.Store, ev Events, args []string, out io.Writer) error {
+	tag, err := parseTagArgs(args)
+	if err != nil {
+		return err
+	}
+
+	var tagged []*project.Project
+	err = store.WithTx(func(tx project.Store) error {
+		projects, err := tx.GetAll()
+		if err != nil {
+			return err
+		}
+		for _, p := range projects {
+			if !p.Running {
+				continue
+			}
+			t, err := tracker.Tag(tx, p.ID, tag)
+			if err != nil {
+				return err
+			}
+			tagged = append(tagged, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(tagged) == 0 {
+		return errors.New("no timer is running")
+	}
+	for _, p := range tagged {
+		fmt.Fprintf(out, "Tagged %s [%s]\n", p.Name, tag)
+	}
+	return nil
+}
+
+// runAdd records a session that was not timed. Times are HH:MM today or
+// YYYY-MM-DD HH:MM; clock times that end before they start run past midnight.
+func runAdd(store project.Store, ev Events, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	from := fs.String("from", "", "start of the session")
+	to := fs.String("to", "", "end of the session")
+	tag := fs.String("tag", "", "tag for the session")
+	note := fs.String("note", "", "note for the session")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 || *from == "" || *to == "" {
+		return errors.New("usage: timer_tui add <project> --from HH:MM --to HH:MM [--tag TAG] [--note TEXT]")
+	}
+
+	p, err := findProject(store, strings.Join(positional, " "))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	start, err := parseLogTime(*from, now)
+	if err != nil {
+		return err
+	}
+	stop, err := parseLogTime(*to, now)
+	if err != nil {
+		return err
+	}
+	if _, err := time.Parse("15:04", *from); err == nil && !stop.After(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+
+	var added *project.Project
+	var log timelog.TimeLog
+	err = store.WithTx(func(tx project.Store) error {
+		var err error
+		added, log, err = tracker.AddLog(tx, timelog.TimeLog{ProjectID: p.ID, StartedAt: start, StoppedAt: stop, Tags: timelog.ParseTags(*tag), Note: *note}, now)
+		if err != nil {
+			return err
+		}
+		return ev.queue(tx, logEvents(added, []timelog.TimeLog{log}))
+	})
+	if err != nil {
+		return err
+	}
+
+	suffix := ""
+	if len(log.Tags) > 0 {
+		suffix = " [" + timelog.FormatTags(log.Tags) + "]"
+	}
+	fmt.Fprintf(out, "Added %s %s-%s (%s)%s\n", added.Name,
+		log.StartedAt.Format("Jan 02 15:04"), log.StoppedAt.Format("15:04"), formatDuration(log.Duration), suffix)
+	ev.fire(logEvents(added, []timelog.TimeLog{log})...)
+	return nil
+}
+
+func loggedDuration(logs []timelog.TimeLog) time.Duration {
+	var total time.Duration
+	for _, l := range logs {
+		total += l.Duration
+	}
+	return total
+}
+
+func runStatus(store project.Store, ev Events, args []string, out io.Writer) error {
+	if len(args) > 0 {
+		return errors.New("usage: timer_tui status")
+	}
+
+	// Pomodoro projects are brought up to date first so the phase shown is
+	// the current one and finished work phases are logged. Timers set to
+	// stop at their budget are stopped if it ran out.
+	now := time.Now()
+	var projects []project.Project
+	var autoStopped []*tracker.AutoStopped
+	var events []hooks.Payload
+	err := store.WithTx(func(tx project.Store) error {
+		all, err := tx.GetAll()
+		if err != nil {
+			return err
+		}
+		for _, p := range all {
+			if !p.Running {
+				continue
+			}
+			moved, logs, err := tracker.Advance(tx, p.ID, now)
+			if err != nil {
+				return err
+			}
+			events = append(events, logEvents(moved, logs)...)
+			auto, err := tracker.AutoStop(tx, p.ID, now)
+			if err != nil {
+				return err
+			}
+			if auto != nil {
+				autoStopped = append(autoStopped, auto)
+				events = append(events, stoppedEvents([]tracker.Stopped{auto.Stopped}, auto.At)...)
+				if auto.Next != nil {
+					events = append(events, hooks.ProjectEvent(hooks.Start, auto.Next, auto.At))
+				}
+			}
+		}
+		if projects, err = tx.GetAll(); err != nil {
+			return err
+		}
+		return ev.queue(tx, events)
+	})
+	if err != nil {
+		return err
+	}
+	ev.fire(events...)
+	for _, a := range autoStopped {
+		fmt.Fprintf(out, "Stopped %s at its budget (%s)\n", a.Project.Name, formatDuration(loggedDuration(a.Logs)))
+		if a.Next != nil {
+			fmt.Fprintf(out, "Started %s\n", a.Next.Name)
+		}
+	}
+	writeStatus(out, projects, now)
+	return nil
+}
+
+// writeStatus prints the running timers among projects, as stored.
+func writeStatus(out io.Writer, projects []project.Project, now time.Time) {
+	running := false
+	for _, p := range projects {
+		if !p.Running {
+			continue
+		}
+		running = true
+		elapsed := p.ElapsedAt(now)
+		remaining := "no budget"
+		if ot := p.OverBudget(0, elapsed); ot > 0 {
+			remaining = formatOvertime(ot) + " overtime"
+		} else if !p.OpenEnded() {
+			remaining = formatDuration(p.MaxTime-elapsed) + " remaining"
+		}
+		if p.Pomodoro != nil {
+			fmt.Fprintf(out, "%s: %s %d/%d, %s left in phase, %s elapsed, %s\n",
+				p.Name, p.Phase, p.PomodoroCycle(), p.Pomodoro.Cycles,
+				formatDuration(p.PhaseEnd().Sub(now)), formatDuration(elapsed), remaining)
+			continue
+		}
+		session := ""
+		if !p.StartedAt.IsZero() {
+			session = fmt.Sprintf(" (session %s)", formatDuration(now.Sub(p.StartedAt)))
+		}
+		fmt.Fprintf(out, "%s: running for %s, %s%s\n",
+			p.Name, formatDuration(elapsed), remaining, session)
+	}
+	if !running {
+		fmt.Fprintln(out, "No timer running")
+	}
+}
+
+func runList(store project.Store, ev Events, args []string, out io.Writer) error {
+	if len(args) > 0 {
+		return errors.New("usage: timer_tui list")
+	}
+
+	projects, err := store.GetAll()
+	if err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		fmt.Fprintln(out, "No projects")
+		return nil
+	}
+
+	now := time.Now()
+	for _, p := range projects {
+		running := ""
+		if p.Running {
+			running = " ●"
+		}
+		budget := formatDuration(p.MaxTime)
+		if p.OpenEnded() {
+			budget = "-"
+		} else if ot := p.OverBudget(0, p.ElapsedAt(now)); ot > 0 {
+			budget += " " + formatOvertime(ot)
+		}
+		fmt.Fprintf(out, "%4d  %-24s %9s / %s%s\n",
+			p.ID, p.Name, formatDuration(p.ElapsedAt(now)), budget, running)
+	}
+	return nil
+}
+
+func runLog(store project.Store, ev Events, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("log", flag.ContinueOnError)
+	projectRef := fs.String("project", "", "only show logs for this project")
+	limit := fs.Int("n", 20, "number of entries to show (0 for all)")
+	search := fs.String("search", "", "only show logs with a tag or note that contains this")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return errors.New("usage: timer_tui log [--project NAME] [--search TEXT] [-n COUNT]")
+	}
+
+	f := project.LogFilter{Search: *search}
+	if *projectRef != "" {
+		p, err := findProject(store, *projectRef)
+		if err != nil {
+			return err
+		}
+		f.ProjectID = p.ID
+	}
+	logs, err := store.FindLogs(f)
+	if err != nil {
+		return err
+	}
+	if *limit > 0 && len(logs) > *limit {
+		logs = logs[:*limit]
+	}
+
+	for _, lp := range logs {
+		fmt.Fprintf(out, "%s  %-24s %9s  %s\n",
+			lp.Log.StartedAt.Local().Format("2006-01-02 15:04"),
+			lp.ProjectName,
+			formatDuration(lp.Log.Duration),
+			timelog.FormatTags(lp.Log.Tags),
+		)
+		if lp.Log.Note != "" {
+			fmt.Fprintf(out, "    %s\n", strings.ReplaceAll(lp.Log.Note, "\n", "\n    "))
+		}
+	}
+	return nil
+}
+
+func runReport(store project.Store, ev Events, args []string, out io.Writer) error {
+	if len(args) > 0 {
+		return errors.New("usage: timer_tui report")
+	}
+
+	totals, err := store.GetTotals()
+	if err != nil {
+		return err
+	}
+	if len(totals) == 0 {
+		fmt.Fprintln(out, "No projects")
+		return nil
+	}
+
+	fmt.Fprintf(out, "%-24s %10s %10s %s\n", "Project", "In budget", "Overtime", "Overrun sessions")
+	for _, t := range totals {
+		overtime := "-"
+		if t.Overtime > 0 {
+			overtime = formatOvertime(t.Overtime)
+		}
+		fmt.Fprintf(out, "%-24s %10s %10s %d/%d\n",
+			t.ProjectName, formatDuration(t.InBudget()), overtime, t.Overruns, t.Sessions)
+	}
+	return nil
+}
+
+const tagsUsage = "usage: timer_tui tags [rename OLD NEW | merge TAG... --into TAG]"
+
+// runTags lists the tags in use with their totals, or renames or merges
+// them on every log.
+func runTags(store project.Store, ev Events, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return listTags(store, out)
+	}
+	switch args[0] {
+	case "rename":
+		if len(args) != 3 {
+			return errors.New(tagsUsage)
+		}
+		from, to := strings.TrimSpace(args[1]), timelog.ParseTags(args[2])
+		if len(to) != 1 {
+			return fmt.Errorf("invalid tag name %q", args[2])
+		}
+		if err := store.WithTx(func(tx project.Store) error { return tx.RenameTag(from, to[0]) }); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Renamed %s to %s\n", from, to[0])
+	case "merge":
+		fs := flag.NewFlagSet("tags merge", flag.ContinueOnError)
+		into := fs.String("into", "", "tag to merge into")
+		sources, err := parseArgs(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		to := timelog.ParseTags(*into)
+		if len(sources) == 0 || len(to) != 1 {
+			return errors.New(tagsUsage)
+		}
+		err = store.WithTx(func(tx project.Store) error {
+			for _, from := range sources {
+				if err := tx.RenameTag(strings.TrimSpace(from), to[0]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Merged %s into %s\n", strings.Join(sources, ", "), to[0])
+	default:
+		return errors.New(tagsUsage)
+	}
+	return nil
+}
+
+func listTags(store project.Store, out io.Writer) error {
+	totals, err := store.GetTagTotals()
+	if err != nil {
+		return err
+	}
+	if len(totals) == 0 {
+		fmt.Fprintln(out, "No tags")
+		return nil
+	}
+
+	fmt.Fprintf(out, "%-24s %10s %10s %s\n", "Tag", "Logged", "Overtime", "Sessions")
+	for _, t := range totals {
+		overtime := "-"
+		if t.Overtime > 0 {
+			overtime = formatOvertime(t.Overtime)
+		}
+		fmt.Fprintf(out, "%-24s %10s %10s %d\n", t.Tag, formatDuration(t.Logged), overtime, t.Sessions)
+	}
+	return nil
+}
+
+// runWebhooks attempts queued webhook deliveries that are due and lists what
+// is still waiting.
+func runWebhooks(store project.Store, ev Events, args []string, out io.Writer) error {
+	if len(args) > 0 {
+		return errors.New("usage: timer_tui webhooks")
+	}
+
+	if err := ev.Webhooks.Flush(time.Now()); err != nil {
+		return err
+	}
+	pending, err := store.GetOutbox(time.Time{})
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(out, "No webhook deliveries pending")
+		return nil
+	}
+
+	for _, msg := range pending {
+		fmt.Fprintf(out, "%4d  %-6s %s  attempts %d, next %s\n",
+			msg.ID, msg.Event, msg.URL, msg.Attempts, msg.NextAttemptAt.Local().Format("2006-01-02 15:04:05"))
+		if msg.LastError != "" {
+			fmt.Fprintf(out, "      %s\n", msg.LastError)
+		}
+	}
+	return nil
+}
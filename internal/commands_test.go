@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"timer_tui/internal/project"
+)
+
+// run runs a headless command against store and returns what it printed.
+func run(t *testing.T, store project.Store, name string, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := RunCommand(store, Events{}, name, args, &out); err != nil {
+		t.Fatalf("%s %s: %v", name, strings.Join(args, " "), err)
+	}
+	return out.String()
+}
+
+func TestCommandsStartStopStatusLog(t *testing.T) {
+	store := project.NewMemoryStore()
+	for _, name := range []string{"Write", "Review code"} {
+		if _, err := store.Create(name, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if out := run(t, store, "status"); out != "No timer running\n" {
+		t.Errorf("status = %q, want no timer running", out)
+	}
+	if out := run(t, store, "start", "write", "--tag", "draft"); out != "Started Write\n" {
+		t.Errorf("start = %q", out)
+	}
+	if out := run(t, store, "status"); !strings.HasPrefix(out, "Write: running for 00:00, ") || !strings.HasSuffix(out, "(session 00:00)\n") {
+		t.Errorf("status = %q, want Write running", out)
+	}
+
+	// Starting another project stops the running one, logged with the tag
+	// it was started with.
+	out := run(t, store, "start", "review", "code")
+	if !strings.Contains(out, "Stopped Write") || !strings.HasSuffix(out, "Started Review code\n") {
+		t.Errorf("start = %q, want Write stopped and Review code started", out)
+	}
+	if out := run(t, store, "stop", "--tag", "pr"); !strings.HasPrefix(out, "Stopped Review code") || !strings.HasSuffix(out, "[pr]\n") {
+		t.Errorf("stop = %q", out)
+	}
+	if err := RunCommand(store, Events{}, "stop", nil, &bytes.Buffer{}); err == nil {
+		t.Error("stop with nothing running succeeded")
+	}
+
+	logs := run(t, store, "log")
+	lines := strings.Split(strings.TrimSpace(logs), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "pr") || !strings.HasSuffix(lines[1], "draft") {
+		t.Errorf("log = %q, want the pr session then the draft one", logs)
+	}
+	if out := run(t, store, "log", "--project", "Write"); strings.Count(out, "\n") != 1 {
+		t.Errorf("log --project Write = %q, want one entry", out)
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	appName = "timer_tui"
+
+	// DBFileName is the database file name, both in the data directory and in
+	// the working directory where older versions created it.
+	DBFileName = "timer_tui.db"
+
+	// DBEnvVar overrides the database location when --db is not given.
+	DBEnvVar = "TIMER_TUI_DB"
+
+	// HooksFileName is the event hooks file in the config directory.
+	HooksFileName = "hooks.conf"
+
+	// WebhooksFileName is the webhook settings file in the config directory.
+	WebhooksFileName = "webhooks.conf"
+
+	// IdleFileName is the idle detection settings file in the config
+	// directory.
+	IdleFileName = "idle.conf"
+
+	// APITokenFileName is the file in the data directory holding the
+	// bearer token the HTTP API requires.
+	APITokenFileName = "api_token"
+)
+
+// DataDir returns the directory for persistent app data:
+// $XDG_DATA_HOME/timer_tui, or ~/.local/share/timer_tui when unset.
+func DataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.New("cannot determine data directory: set XDG_DATA_HOME or use --db")
+	}
+	return filepath.Join(home, ".local", "share", appName), nil
+}
+
+// APITokenPath returns the path of the HTTP API token file.
+func APITokenPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, APITokenFileName), nil
+}
+
+// ConfigDir returns the directory for user configuration:
+// $XDG_CONFIG_HOME/timer_tui, or ~/.config/timer_tui when unset.
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.New("cannot determine config directory: set XDG_CONFIG_HOME")
+	}
+	return filepath.Join(home, ".config", appName), nil
+}
+
+// HooksPath returns the path of the event hooks file.
+func HooksPath() (string, error) {
+	return configFile(HooksFileName)
+}
+
+// WebhooksPath returns the path of the webhook settings file.
+func WebhooksPath() (string, error) {
+	return configFile(WebhooksFileName)
+}
+
+// IdlePath returns the path of the idle detection settings file.
+func IdlePath() (string, error) {
+	return configFile(IdleFileName)
+}
+
+func configFile(name string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// RuntimeDir returns the per-user directory for sockets:
+// $XDG_RUNTIME_DIR/timer_tui, or timer_tui-<uid> in the temp directory when
+// unset.
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName)
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("%s-%d", appName, os.Getuid()))
+}
+
+// SocketPath returns the control socket of a TUI running on the database at
+// dbPath. Each database gets its own socket, so commands given --db reach
+// the instance using that database.
+func SocketPath(dbPath string) string {
+	return filepath.Join(RuntimeDir(), "control-"+dbKey(dbPath)+".sock")
+}
+
+// PromptCachePath returns the file `timer_tui prompt` caches the running
+// timer of the database at dbPath in.
+func PromptCachePath(dbPath string) string {
+	return filepath.Join(RuntimeDir(), "prompt-"+dbKey(dbPath)+".json")
+}
+
+// dbKey identifies a database in file names, by a hash of its absolute path.
+func dbKey(dbPath string) string {
+	if abs, err := filepath.Abs(dbPath); err == nil {
+		dbPath = abs
+	}
+	sum := sha256.Sum256([]byte(dbPath))
+	return hex.EncodeToString(sum[:6])
+}
+
+// ResolveDBPath picks the database path from the --db flag, then the
+// TIMER_TUI_DB environment variable, then the data directory. isDefault
+// reports whether neither override was given.
+func ResolveDBPath(flagValue string) (path string, isDefault bool, err error) {
+	if flagValue != "" {
+		return flagValue, false, nil
+	}
+	if env := os.Getenv(DBEnvVar); env != "" {
+		return env, false, nil
+	}
+	dir, err := DataDir()
+	if err != nil {
+		return "", false, err
+	}
+	return filepath.Join(dir, DBFileName), true, nil
+}
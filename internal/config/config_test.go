@@ -0,0 +1,41 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveDBPath(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/data")
+	t.Setenv(DBEnvVar, "")
+
+	if path, isDefault, err := ResolveDBPath("/flag.db"); err != nil || path != "/flag.db" || isDefault {
+		t.Errorf("with --db: %q, %v, %v; want /flag.db, not default", path, isDefault, err)
+	}
+
+	want := filepath.Join("/data", "timer_tui", DBFileName)
+	if path, isDefault, err := ResolveDBPath(""); err != nil || path != want || !isDefault {
+		t.Errorf("with nothing set: %q, %v, %v; want %s, default", path, isDefault, err, want)
+	}
+
+	t.Setenv(DBEnvVar, "/env.db")
+	if path, isDefault, err := ResolveDBPath(""); err != nil || path != "/env.db" || isDefault {
+		t.Errorf("with %s: %q, %v, %v; want /env.db, not default", DBEnvVar, path, isDefault, err)
+	}
+	if path, _, _ := ResolveDBPath("/flag.db"); path != "/flag.db" {
+		t.Errorf("--db with %s set = %q, want the flag to win", DBEnvVar, path)
+	}
+}
+
+// A relative XDG_DATA_HOME is invalid by the spec and is ignored.
+func TestDataDirIgnoresRelativeXDG(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "relative")
+	t.Setenv("HOME", "/home/someone")
+	dir, err := DataDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("/home/someone", ".local", "share", "timer_tui"); dir != want {
+		t.Errorf("data dir = %q, want %q", dir, want)
+	}
+}
@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"timer_tui/internal/control"
+	"timer_tui/internal/project"
+	"timer_tui/internal/tracker"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// MsgControl is a request that came in on the control socket. The model
+// answers it on Reply.
+type MsgControl struct {
+	Request control.Request
+	Reply   chan<- control.Response
+}
+
+// controlTimeout is how long a request waits for the model to answer, in
+// case the program is exiting.
+const controlTimeout = 5 * time.Second
+
+// ControlHandler passes control requests to the program's model and waits
+// for its answers.
+func ControlHandler(p *tea.Program) func(control.Request) control.Response {
+	return func(req control.Request) control.Response {
+		reply := make(chan control.Response, 1)
+		p.Send(MsgControl{Request: req, Reply: reply})
+		select {
+		case resp := <-reply:
+			return resp
+		case <-time.After(controlTimeout):
+			return control.Response{Error: "the running instance did not answer"}
+		}
+	}
+}
+
+// remoteCommands are the subcommands a running TUI carries out itself, with
+// the request each sends.
+var remoteCommands = map[string]func(args []string) (control.Request, error){
+	"start": func(args []string) (control.Request, error) {
+		ref, tag, err := parseStartArgs(args)
+		return control.Request{Cmd: control.Start, Project: ref, Tag: tag}, err
+	},
+	"stop": func(args []string) (control.Request, error) {
+		tag, err := parseStopArgs(args)
+		return control.Request{Cmd: control.Stop, Tag: tag}, err
+	},
+	"tag": func(args []string) (control.Request, error) {
+		tag, err := parseTagArgs(args)
+		return control.Request{Cmd: control.Tag, Tag: tag}, err
+	},
+	"status": func(args []string) (control.Request, error) {
+		if len(args) > 0 {
+			return control.Request{}, errors.New("usage: timer_tui status")
+		}
+		return control.Request{Cmd: control.Query}, nil
+	},
+}
+
+// RunRemote sends the named subcommand to the TUI listening on socket, if
+// there is one and it handles that command. handled is false when the
+// command should run against the database instead.
+func RunRemote(socket, name string, args []string, out io.Writer) (handled bool, err error) {
+	build, ok := remoteCommands[name]
+	if !ok {
+		return false, nil
+	}
+	req, err := build(args)
+	if err != nil {
+		return true, err
+	}
+	resp, err := control.Send(socket, req)
+	if errors.Is(err, control.ErrNoInstance) {
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	fmt.Fprint(out, resp.Output)
+	return true, nil
+}
+
+func (m *Model) handleControl(req control.Request) control.Response {
+	if len(m.Recoveries) > 0 {
+		return control.Response{Error: "the running instance is waiting for an interrupted session to be resolved"}
+	}
+
+	var out strings.Builder
+	var err error
+	switch req.Cmd {
+	case control.Start:
+		err = m.controlStart(&out, req.Project, req.Tag)
+	case control.Stop:
+		err = m.controlStop(&out, req.Tag)
+	case control.Tag:
+		err = m.controlTag(&out, req.Tag)
+	case control.Query:
+		return m.controlQuery()
+	default:
+		err = fmt.Errorf("unknown command %q", req.Cmd)
+	}
+	if err != nil {
+		return control.Response{Error: err.Error()}
+	}
+	return control.Response{Output: out.String()}
+}
+
+// commitForControl saves a session waiting at the tag prompt with tag, so a
+// command from outside does not leave it half stopped.
+func (m *Model) commitForControl(out io.Writer, tag string) (bool, error) {
+	if m.PendingLog == nil {
+		return false, nil
+	}
+	stopped := m.commitPendingLog(tag, m.NoteInput)
+	if stopped == nil {
+		return true, m.Err
+	}
+	writeStopped(out, []tracker.Stopped{*stopped})
+	return true, nil
+}
+
+func (m *Model) controlStart(out io.Writer, ref, tag string) error {
+	projects := make([]project.Project, len(m.Projects))
+	for i, p := range m.Projects {
+		projects[i] = *p
+	}
+	p, err := matchProject(projects, ref)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.commitForControl(out, ""); err != nil {
+		return err
+	}
+	stopped, err := m.startProject(p.ID, tag)
+	if errors.Is(err, tracker.ErrAlreadyRunning) {
+		return fmt.Errorf("%s is already running", p.Name)
+	}
+	if err != nil {
+		return err
+	}
+	for _, s := range stopped {
+		fmt.Fprintf(out, "Stopped %s (%s)\n", s.Project.Name, formatDuration(loggedDuration(s.Logs)))
+	}
+	fmt.Fprintf(out, "Started %s\n", p.Name)
+	return nil
+}
+
+func (m *Model) controlStop(out io.Writer, tag string) error {
+	committed, err := m.commitForControl(out, tag)
+	if err != nil || committed {
+		return err
+	}
+
+	now := time.Now()
+	var stopped []tracker.Stopped
+	for _, p := range m.Projects {
+		if !p.Running {
+			continue
+		}
+		s, err := m.stopProject(p.ID, now, tag, "")
+		if err != nil {
+			return err
+		}
+		stopped = append(stopped, s)
+	}
+	if len(stopped) == 0 {
+		return errors.New("no timer is running")
+	}
+	writeStopped(out, stopped)
+	return nil
+}
+
+// controlTag tags the running session. A session waiting at the tag prompt
+// is saved with the tag, as if it had been typed there.
+func (m *Model) controlTag(out io.Writer, tag string) error {
+	committed, err := m.commitForControl(out, tag)
+	if err != nil || committed {
+		return err
+	}
+
+	tagged := false
+	for _, p := range m.Projects {
+		if !p.Running {
+			continue
+		}
+		var stored *project.Project
+		err := m.store.WithTx(func(tx project.Store) error {
+			var err error
+			stored, err = tracker.Tag(tx, p.ID, tag)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		m.syncProject(stored)
+		fmt.Fprintf(out, "Tagged %s [%s]\n", p.Name, tag)
+		tagged = true
+	}
+	if !tagged {
+		return errors.New("no timer is running")
+	}
+	return nil
+}
+
+func (m *Model) controlQuery() control.Response {
+	now := time.Now()
+	var out strings.Builder
+	var resp control.Response
+	projects := make([]project.Project, len(m.Projects))
+	for i, p := range m.Projects {
+		projects[i] = *m.storedProject(p)
+		if p.Running {
+			resp.Running = append(resp.Running, control.Timer{
+				ProjectID: p.ID,
+				Project:   p.Name,
+				Elapsed:   seconds(projects[i].ElapsedAt(now)),
+				Phase:     string(p.Phase),
+			})
+		}
+	}
+	writeStatus(&out, projects, now)
+	resp.Output = out.String()
+	return resp
+}
@@ -0,0 +1,166 @@
+// Package control lets commands reach a running TUI instead of writing to
+// the database behind its back. The TUI listens on a Unix socket; each
+// connection carries one JSON request line and gets one JSON response line.
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// Commands a running instance accepts.
+const (
+	Start = "start" // start Project's timer, tagging the session with Tag
+	Stop  = "stop"  // stop the running timer and log it with Tag
+	Tag   = "tag"   // set the tag the running session is logged with
+	Query = "query" // report the running timers
+)
+
+// connTimeout bounds how long either side waits on the other.
+const connTimeout = 10 * time.Second
+
+var (
+	// ErrNoInstance is returned by Send when nothing listens on the socket.
+	ErrNoInstance = errors.New("no running instance")
+	// ErrInUse is returned by Listen when another instance holds the socket.
+	ErrInUse = errors.New("another instance is listening")
+)
+
+type Request struct {
+	Cmd     string `json:"cmd"`
+	Project string `json:"project,omitempty"` // name or ID, for start
+	Tag     string `json:"tag,omitempty"`
+}
+
+// Timer is a running timer as reported by a query.
+type Timer struct {
+	ProjectID int64  `json:"project_id"`
+	Project   string `json:"project"`
+	Elapsed   int64  `json:"elapsed_seconds"`
+	Phase     string `json:"phase,omitempty"`
+}
+
+type Response struct {
+	Error string `json:"error,omitempty"`
+	// Output is what the matching command prints when run on its own.
+	Output  string  `json:"output,omitempty"`
+	Running []Timer `json:"running,omitempty"`
+}
+
+// Listener accepts control connections on a Unix socket.
+type Listener struct {
+	ln   net.Listener
+	path string
+}
+
+// Listen creates the socket at path, replacing one left behind by an
+// instance that died. The socket is only accessible to the current user.
+func Listen(path string) (*Listener, error) {
+	if err := privateDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, ErrInUse
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return &Listener{ln: ln, path: path}, nil
+}
+
+// privateDir creates dir if needed and checks that only the current user
+// can use it, since it may live in a shared temp directory.
+func privateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !info.IsDir() || (ok && int(st.Uid) != os.Getuid()) || info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("%s must be a directory private to the current user", dir)
+	}
+	return nil
+}
+
+// Serve answers requests with handle until the listener is closed.
+func (l *Listener) Serve(handle func(Request) Response) error {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go serveConn(conn, handle)
+	}
+}
+
+func serveConn(conn net.Conn, handle func(Request) Response) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(connTimeout))
+
+	var req Request
+	var resp Response
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return
+	}
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp.Error = "invalid request: " + err.Error()
+	} else {
+		resp = handle(req)
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// Close stops listening and removes the socket.
+func (l *Listener) Close() error {
+	err := l.ln.Close()
+	os.Remove(l.path)
+	return err
+}
+
+// Send sends req to the instance listening at path and returns its
+// response. A response reporting an error is returned as that error.
+func Send(path string, req Request) (Response, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return Response{}, ErrNoInstance
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(connTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,84 @@
+package control
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSendReachesListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "control.sock")
+	if _, err := Send(path, Request{Cmd: Query}); !errors.Is(err, ErrNoInstance) {
+		t.Fatalf("Send before Listen: %v, want ErrNoInstance", err)
+	}
+
+	l, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.Serve(func(req Request) Response {
+		if req.Cmd != Start {
+			return Response{Error: "unsupported " + req.Cmd}
+		}
+		return Response{Output: "Started " + req.Project + " [" + req.Tag + "]\n"}
+	})
+
+	if _, err := Listen(path); !errors.Is(err, ErrInUse) {
+		t.Errorf("second Listen: %v, want ErrInUse", err)
+	}
+
+	resp, err := Send(path, Request{Cmd: Start, Project: "Write", Tag: "docs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Output != "Started Write [docs]\n" {
+		t.Errorf("output = %q", resp.Output)
+	}
+	if _, err := Send(path, Request{Cmd: Stop}); err == nil || err.Error() != "unsupported stop" {
+		t.Errorf("Send stop: %v, want the handler's error", err)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket left behind after Close: %v", err)
+	}
+}
+
+// A socket left by an instance that died is replaced.
+func TestListenReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "control.sock")
+	l, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Closing the listener without removing the file is what a crash
+	// leaves behind.
+	l.ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.ln.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Listen(path)
+	if err != nil {
+		t.Fatalf("Listen over a stale socket: %v", err)
+	}
+	l.Close()
+}
+
+func TestListenRefusesSharedDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(dir, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(filepath.Join(dir, "control.sock")); err == nil {
+		t.Fatal("Listen in a world-writable directory succeeded")
+	}
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"time"
+
+	"timer_tui/internal/hooks"
+	"timer_tui/internal/project"
+	"timer_tui/internal/timelog"
+	"timer_tui/internal/tracker"
+	"timer_tui/internal/webhook"
+)
+
+// Events are where timer events go besides the database: the user's hooks
+// and webhooks. The zero value sends them nowhere.
+type Events struct {
+	Hooks    *hooks.Runner
+	Webhooks *webhook.Emitter
+	// Detach, if set, hands the webhook deliveries still due when the
+	// process exits to a process of their own, so exiting never waits on
+	// an endpoint.
+	Detach func() error
+}
+
+// queue adds the webhook deliveries of events to the outbox in tx, so they
+// are stored with the change they describe.
+func (e Events) queue(tx project.Store, events []hooks.Payload) error {
+	for _, p := range events {
+		if err := e.Webhooks.Enqueue(tx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fire runs the hooks for events whose transaction committed and wakes
+// webhook delivery.
+func (e Events) fire(events ...hooks.Payload) {
+	for _, p := range events {
+		e.Hooks.Fire(p)
+	}
+	e.Webhooks.Wake()
+}
+
+// Wait lets running hooks finish and detaches webhook deliveries that are
+// still due, for when the process is about to exit.
+func (e Events) Wait() error {
+	e.Hooks.Wait()
+	if e.Detach == nil {
+		return nil
+	}
+	due, err := e.Webhooks.Due(time.Now())
+	if err != nil || !due {
+		return err
+	}
+	return e.Detach()
+}
+
+// stoppedEvents are the log and stop events for timers the tracker stopped
+// at now.
+func stoppedEvents(stopped []tracker.Stopped, now time.Time) []hooks.Payload {
+	var events []hooks.Payload
+	for _, s := range stopped {
+		events = append(events, logEvents(s.Project, s.Logs)...)
+		// The session cut short by the stop is logged last, ending at now;
+		// a stop during a pomodoro break has no session.
+		if n := len(s.Logs); n > 0 && s.Logs[n-1].StoppedAt.Equal(now) {
+			events = append(events, hooks.SessionEvent(hooks.Stop, s.Project, s.Logs[n-1]))
+		} else {
+			events = append(events, hooks.ProjectEvent(hooks.Stop, s.Project, now))
+		}
+	}
+	return events
+}
+
+func logEvents(p *project.Project, logs []timelog.TimeLog) []hooks.Payload {
+	events := make([]hooks.Payload, len(logs))
+	for i, l := range logs {
+		events[i] = hooks.SessionEvent(hooks.Log, p, l)
+	}
+	return events
+}
+
+// deletedLogEvents builds the log event for l, which was deleted from p.
+func deletedLogEvents(p *project.Project, l timelog.TimeLog) []hooks.Payload {
+	payload := hooks.SessionEvent(hooks.Log, p, l)
+	payload.Deleted = true
+	return []hooks.Payload{payload}
+}
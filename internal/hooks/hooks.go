@@ -0,0 +1,248 @@
+// Package hooks runs user-defined shell commands when timers start, stop,
+// complete or reset and when time logs are written.
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"timer_tui/internal/project"
+	"timer_tui/internal/timelog"
+)
+
+type Event string
+
+const (
+	Start    Event = "start"
+	Stop     Event = "stop"
+	Complete Event = "complete"
+	Reset    Event = "reset"
+	Log      Event = "log"
+)
+
+var events = []Event{Start, Stop, Complete, Reset, Log}
+
+// DefaultTimeout bounds how long a hook may run before it is killed.
+const DefaultTimeout = 10 * time.Second
+
+// Payload describes an event. It is passed to hooks as JSON on stdin and as
+// TIMER_TUI_* environment variables.
+type Payload struct {
+	Event     Event     `json:"event"`
+	Time      time.Time `json:"time"`
+	ProjectID int64     `json:"project_id"`
+	Project   string    `json:"project"`
+	// Tag lists Tags comma separated, as they were written before a session
+	// could have several.
+	Tag  string   `json:"tag,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	Note string   `json:"note,omitempty"`
+	// Elapsed is the project's total time; Duration is the session's.
+	Elapsed   float64    `json:"elapsed_seconds"`
+	Duration  float64    `json:"duration_seconds,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+	LogID     int64      `json:"log_id,omitempty"`
+	// Deleted marks a log event for a log that was deleted. A log that was
+	// edited is sent again with the same LogID.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// ProjectEvent builds the payload for an event on p at now.
+func ProjectEvent(event Event, p *project.Project, now time.Time) Payload {
+	payload := Payload{
+		Event:     event,
+		Time:      now,
+		ProjectID: p.ID,
+		Project:   p.Name,
+		Elapsed:   p.ElapsedAt(now).Seconds(),
+	}
+	if p.Running && !p.StartedAt.IsZero() {
+		startedAt := p.StartedAt
+		payload.StartedAt = &startedAt
+	}
+	return payload
+}
+
+// SessionEvent builds the payload for an event about a logged session,
+// such as the stop that wrote it or the log itself.
+func SessionEvent(event Event, p *project.Project, l timelog.TimeLog) Payload {
+	payload := ProjectEvent(event, p, l.StoppedAt)
+	payload.Tag = timelog.FormatTags(l.Tags)
+	payload.Tags = l.Tags
+	payload.Note = l.Note
+	payload.Duration = l.Duration.Seconds()
+	payload.StartedAt = &l.StartedAt
+	payload.StoppedAt = &l.StoppedAt
+	if event == Log {
+		payload.LogID = l.ID
+	}
+	return payload
+}
+
+func (p Payload) env() []string {
+	env := []string{
+		"TIMER_TUI_EVENT=" + string(p.Event),
+		"TIMER_TUI_PROJECT=" + p.Project,
+		"TIMER_TUI_PROJECT_ID=" + strconv.FormatInt(p.ProjectID, 10),
+		"TIMER_TUI_TAG=" + p.Tag,
+		"TIMER_TUI_ELAPSED=" + strconv.Itoa(int(p.Elapsed)),
+		"TIMER_TUI_DURATION=" + strconv.Itoa(int(p.Duration)),
+	}
+	if p.Note != "" {
+		env = append(env, "TIMER_TUI_NOTE="+p.Note)
+	}
+	if p.StartedAt != nil {
+		env = append(env, "TIMER_TUI_STARTED_AT="+p.StartedAt.Format(time.RFC3339))
+	}
+	if p.StoppedAt != nil {
+		env = append(env, "TIMER_TUI_STOPPED_AT="+p.StoppedAt.Format(time.RFC3339))
+	}
+	if p.Deleted {
+		env = append(env, "TIMER_TUI_DELETED=1")
+	}
+	return env
+}
+
+// Runner runs the configured hooks. A nil *Runner runs nothing, so callers
+// need not check whether any hooks are configured.
+type Runner struct {
+	hooks   map[Event][]string
+	timeout time.Duration
+	wg      sync.WaitGroup
+
+	// Errors, if set, receives a line for each hook that fails.
+	Errors io.Writer
+	mu     sync.Mutex
+}
+
+// Load reads hooks from path. Each line is "event = command", where event
+// is start, stop, complete, reset or log and command is run through sh -c;
+// an event may have several. "timeout = 5s" sets how long hooks may run.
+// Blank lines and lines starting with # are ignored. A missing file means no
+// hooks.
+func Load(path string) (*Runner, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := &Runner{hooks: make(map[Event][]string), timeout: DefaultTimeout}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%s:%d: want \"event = command\"", path, n)
+		}
+
+		if key == "timeout" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("%s:%d: invalid timeout %q", path, n, value)
+			}
+			r.timeout = d
+			continue
+		}
+		if !validEvent(Event(key)) {
+			return nil, fmt.Errorf("%s:%d: unknown event %q", path, n, key)
+		}
+		r.hooks[Event(key)] = append(r.hooks[Event(key)], value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func validEvent(e Event) bool {
+	for _, known := range events {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Fire starts the hooks for the payload's event in the background and
+// returns at once.
+func (r *Runner) Fire(p Payload) {
+	if r == nil {
+		return
+	}
+	commands := r.hooks[p.Event]
+	if len(commands) == 0 {
+		return
+	}
+
+	input, err := json.Marshal(p)
+	if err != nil {
+		r.report(p.Event, err)
+		return
+	}
+	input = append(input, '\n')
+
+	for _, command := range commands {
+		r.wg.Add(1)
+		go func(command string) {
+			defer r.wg.Done()
+			if err := r.run(command, p, input); err != nil {
+				r.report(p.Event, err)
+			}
+		}(command)
+	}
+}
+
+func (r *Runner) run(command string, p Payload, input []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), p.env()...)
+	cmd.Stdin = bytes.NewReader(input)
+	// Output is discarded so hooks cannot draw over the TUI.
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%q timed out after %s", command, r.timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%q: %w", command, err)
+	}
+	return nil
+}
+
+func (r *Runner) report(event Event, err error) {
+	if r.Errors == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.Errors, "%s hook: %v\n", event, err)
+}
+
+// Wait blocks until every hook started so far has finished or timed out.
+func (r *Runner) Wait() {
+	if r == nil {
+		return
+	}
+	r.wg.Wait()
+}
@@ -0,0 +1,96 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeHooks(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hooks")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	r, err := Load(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || r != nil {
+		t.Fatalf("missing file: %v, %v; want no hooks", r, err)
+	}
+
+	r, err = Load(writeHooks(t, "# comment\n\nstart = echo one\nstart = echo two\ntimeout = 5s\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.hooks[Start]) != 2 || r.timeout != 5*time.Second {
+		t.Fatalf("hooks = %v, timeout %s; want two start hooks and 5s", r.hooks, r.timeout)
+	}
+
+	for _, bad := range []string{"start\n", "begin = echo\n", "timeout = soon\n", "stop =\n"} {
+		if _, err := Load(writeHooks(t, bad)); err == nil {
+			t.Errorf("Load(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+// A hook sees the event in its environment and as JSON on stdin, and Wait
+// returns once it has finished.
+func TestFireRunsHookWithPayload(t *testing.T) {
+	dir := t.TempDir()
+	env, stdin := filepath.Join(dir, "env"), filepath.Join(dir, "stdin")
+	r, err := Load(writeHooks(t, `stop = echo "$TIMER_TUI_PROJECT $TIMER_TUI_TAG $TIMER_TUI_DURATION" > `+env+` && cat > `+stdin+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	stop := start.Add(90 * time.Second)
+	r.Fire(Payload{Event: Stop, Time: stop, ProjectID: 1, Project: "Write", Tag: "docs", Duration: 90, StartedAt: &start, StoppedAt: &stop})
+	r.Fire(Payload{Event: Start, Project: "ignored"})
+	r.Wait()
+
+	got, err := os.ReadFile(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(got)) != "Write docs 90" {
+		t.Errorf("env = %q, want \"Write docs 90\"", got)
+	}
+
+	input, err := os.ReadFile(stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p Payload
+	if err := json.Unmarshal(input, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Event != Stop || p.Project != "Write" || !p.StoppedAt.Equal(stop) {
+		t.Errorf("stdin = %s, want the stop payload", input)
+	}
+}
+
+func TestFireReportsFailures(t *testing.T) {
+	r, err := Load(writeHooks(t, "reset = exit 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs bytes.Buffer
+	r.Errors = &errs
+	r.Fire(Payload{Event: Reset})
+	r.Wait()
+	if !strings.HasPrefix(errs.String(), "reset hook: ") {
+		t.Errorf("errors = %q, want a reset hook failure", errs.String())
+	}
+
+	var none *Runner
+	none.Fire(Payload{Event: Reset})
+	none.Wait()
+}
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"time"
+
+	"timer_tui/internal/idle"
+	"timer_tui/internal/project"
+	"timer_tui/internal/tracker"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// idlePoll is how often the idle command is asked while a timer runs.
+const idlePoll = 5 * time.Second
+
+// IdleGap is time the user was away while a timer ran, waiting for them to
+// decide whether it counts.
+type IdleGap struct {
+	ProjectID int64
+	From, To  time.Time
+}
+
+// MsgIdleSample carries the idle command's answer.
+type MsgIdleSample struct {
+	Idle time.Duration
+	At   time.Time
+	Err  error
+}
+
+// SetIdle turns idle detection on with cfg, or off with nil.
+func (m *Model) SetIdle(cfg *idle.Config) {
+	m.idle = cfg
+}
+
+// idleProject returns the project whose session idle time would be logged
+// to. Pomodoros are left alone, as their breaks already pause the timer,
+// and so are sessions another TUI runs.
+func (m *Model) idleProject() *project.Project {
+	for _, p := range m.Projects {
+		if p.Pomodoro == nil && !m.followed[p.ID] && m.Timers[p.ID].Running() {
+			return p
+		}
+	}
+	return nil
+}
+
+// idleFor is how long there has been no input, in the TUI or, going by the
+// idle command, anywhere else.
+func (m *Model) idleFor(now time.Time) time.Duration {
+	idleFor := now.Sub(m.lastInput)
+	if !m.idleSampled.IsZero() {
+		if d := m.idleSample + now.Sub(m.idleSampled); d < idleFor {
+			idleFor = d
+		}
+	}
+	return idleFor
+}
+
+// checkIdle notices the user going away from a running timer and coming
+// back. It returns a command asking the idle command when that is due.
+func (m *Model) checkIdle(now time.Time) tea.Cmd {
+	if m.idle == nil || m.IdleGap != nil {
+		return nil
+	}
+	p := m.idleProject()
+	if p == nil {
+		m.awaySince = time.Time{}
+		return nil
+	}
+
+	idleFor := m.idleFor(now)
+	if idleFor >= m.idle.Threshold {
+		if m.awaySince.IsZero() {
+			m.awaySince = now.Add(-idleFor)
+		}
+	} else if !m.awaySince.IsZero() {
+		m.openIdleGap(p, now.Add(-idleFor))
+	}
+
+	if m.idle.Command == "" || m.idlePolling || now.Sub(m.idleSampled) < idlePoll {
+		return nil
+	}
+	m.idlePolling = true
+	cfg := *m.idle
+	return func() tea.Msg {
+		d, err := cfg.Query()
+		return MsgIdleSample{Idle: d, At: time.Now(), Err: err}
+	}
+}
+
+// comeBack opens the idle prompt if the user was away and has just pressed
+// a key at now, reporting whether it did.
+func (m *Model) comeBack(now time.Time) bool {
+	if m.idle == nil || m.awaySince.IsZero() {
+		return false
+	}
+	if p := m.idleProject(); p != nil {
+		m.openIdleGap(p, now)
+	}
+	m.awaySince = time.Time{}
+	return m.IdleGap != nil
+}
+
+// openIdleGap asks about the time from when the user went away until back,
+// limited to the running session.
+func (m *Model) openIdleGap(p *project.Project, back time.Time) {
+	from := m.awaySince
+	m.awaySince = time.Time{}
+	if start := m.Timers[p.ID].StartedAt(); from.Before(start) {
+		from = start
+	}
+	if back.After(from) {
+		m.IdleGap = &IdleGap{ProjectID: p.ID, From: from, To: back}
+	}
+}
+
+func (m *Model) closeIdleGap() {
+	m.IdleGap = nil
+	m.IdleSplit = false
+	m.IdleTagInput = ""
+	m.Err = nil
+}
+
+func (m *Model) handleIdleInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.IdleSplit {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			m.IdleSplit = false
+			m.IdleTagInput = ""
+			m.Err = nil
+		case "enter":
+			if err := m.splitIdle(m.IdleTagInput); err != nil {
+				m.Err = err
+				break
+			}
+			m.closeIdleGap()
+		case "backspace":
+			if len(m.IdleTagInput) > 0 {
+				m.IdleTagInput = m.IdleTagInput[:len(m.IdleTagInput)-1]
+			}
+		default:
+			runes := []rune(msg.String())
+			if len(runes) == 1 {
+				m.IdleTagInput += string(runes[0])
+			}
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "k", "enter", "esc":
+		m.closeIdleGap()
+	case "d":
+		// The session is stopped as of when the user left and goes to the
+		// tag prompt like any other stop.
+		gap := m.IdleGap
+		m.closeIdleGap()
+		if p := m.projectByID(gap.ProjectID); p != nil && m.Timers[p.ID].Running() {
+			m.beginStop(p, m.Timers[p.ID], gap.From)
+		}
+	case "s":
+		m.IdleSplit = true
+		m.IdleTagInput = ""
+	}
+	return m, nil
+}
+
+// splitIdle logs the idle time separately with tag and carries on the
+// session after it.
+func (m *Model) splitIdle(tag string) error {
+	gap := m.IdleGap
+	var split tracker.Stopped
+	err := m.store.WithTx(func(tx project.Store) error {
+		var err error
+		if split, err = tracker.Split(tx, gap.ProjectID, gap.From, gap.To, tag); err != nil {
+			return err
+		}
+		if m.owned[gap.ProjectID] {
+			if err := tx.TouchSession(gap.ProjectID, m.owner, gap.To); err != nil {
+				return err
+			}
+		}
+		return m.events.queue(tx, logEvents(split.Project, split.Logs))
+	})
+	if err != nil {
+		m.reloadProject(gap.ProjectID)
+		return err
+	}
+	m.syncProject(split.Project)
+	m.prependLogs(gap.ProjectID, split.Logs)
+	m.events.fire(logEvents(split.Project, split.Logs)...)
+	return nil
+}
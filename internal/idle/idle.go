@@ -0,0 +1,99 @@
+// Package idle configures how the TUI notices that the user walked away
+// from a running timer: no key pressed in the TUI for a while and, with an
+// idle command set up, no input anywhere on the machine either.
+package idle
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commandTimeout bounds a run of the idle command.
+const commandTimeout = 2 * time.Second
+
+type Config struct {
+	// Threshold is how long without input counts as being away.
+	Threshold time.Duration
+	// Command prints how long the machine has been idle, in milliseconds
+	// like xprintidle or as a duration such as "90s". Empty uses key
+	// presses in the TUI alone.
+	Command string
+}
+
+// LoadConfig reads "threshold = 10m" and an optional "command = ..." from
+// path. Blank lines and lines starting with # are ignored. A missing file
+// or one without a threshold yields nil, which leaves detection off.
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cfg Config
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case !ok || value == "":
+			return nil, fmt.Errorf("%s:%d: want \"threshold = ...\" or \"command = ...\"", path, n)
+		case key == "threshold":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("%s:%d: threshold must be a positive duration such as 10m", path, n)
+			}
+			cfg.Threshold = d
+		case key == "command":
+			cfg.Command = value
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", path, n, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if cfg.Threshold == 0 {
+		return nil, nil
+	}
+	return &cfg, nil
+}
+
+// Query runs the idle command and returns how long the machine has been
+// idle.
+func (c Config) Query() (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "sh", "-c", c.Command).Output()
+	if err != nil {
+		return 0, fmt.Errorf("idle command: %w", err)
+	}
+	return Parse(string(out))
+}
+
+// Parse reads an idle time printed as milliseconds or as a duration.
+func Parse(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil && ms >= 0 {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("idle command printed %q, want milliseconds or a duration", s)
+}
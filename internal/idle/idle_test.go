@@ -0,0 +1,34 @@
+package idle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"1500\n": 1500 * time.Millisecond,
+		"0":      0,
+		" 90s ":  90 * time.Second,
+		"2m30s":  150 * time.Second,
+	} {
+		if got, err := Parse(in); err != nil || got != want {
+			t.Errorf("Parse(%q) = %s, %v; want %s", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "soon", "-5", "-1s"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	got, err := Config{Command: "echo 2500"}.Query()
+	if err != nil || got != 2500*time.Millisecond {
+		t.Fatalf("Query() = %s, %v; want 2.5s", got, err)
+	}
+	if _, err := (Config{Command: "exit 1"}).Query(); err == nil {
+		t.Error("failing command: no error")
+	}
+}
@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"timer_tui/internal/hooks"
+	"timer_tui/internal/project"
+	"timer_tui/internal/timelog"
+	"timer_tui/internal/tracker"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// logTimeLayout is how start and stop times are entered in the log form.
+const logTimeLayout = "2006-01-02 15:04"
+
+// logFormFields is the number of inputs on the log form: start, stop, tag,
+// project and note.
+const logFormFields = 5
+
+// LogEdit is the form for adding or editing a logged session: the log as
+// loaded, with a zero ID when adding, and its fields, with the times as
+// parseLogTime reads them and the project by name.
+type LogEdit struct {
+	Log                       project.LogWithProject
+	Start, Stop, Tag, Project string
+	Note                      string
+	Focus                     int
+}
+
+// selectedLog returns the log under the cursor in the log viewer.
+func (m *Model) selectedLog() *project.LogWithProject {
+	if m.LogViewScroll < 0 || m.LogViewScroll >= len(m.AllLogs) {
+		return nil
+	}
+	return &m.AllLogs[m.LogViewScroll]
+}
+
+func (m *Model) openLogEdit(lp project.LogWithProject) {
+	m.LogEdit = &LogEdit{
+		Log:     lp,
+		Start:   lp.Log.StartedAt.Local().Format(logTimeLayout),
+		Stop:    lp.Log.StoppedAt.Local().Format(logTimeLayout),
+		Tag:     timelog.FormatTags(lp.Log.Tags),
+		Project: lp.ProjectName,
+		Note:    lp.Log.Note,
+	}
+	m.Err = nil
+}
+
+// openLogAdd opens the form for a session that was not timed, for the
+// selected project.
+func (m *Model) openLogAdd() {
+	m.LogEdit = &LogEdit{}
+	if p := m.SelectedProject(); p != nil {
+		m.LogEdit.Project = p.Name
+	}
+	m.Err = nil
+}
+
+func (m *Model) handleLogEditInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := m.LogEdit
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		m.LogEdit = nil
+		m.Err = nil
+	case "enter":
+		if f.Focus < logFormFields-1 {
+			f.Focus++
+			break
+		}
+		if m.Err = m.saveLogEdit(); m.Err == nil {
+			m.LogEdit = nil
+		}
+	case "backspace":
+		field := f.field()
+		if len(*field) > 0 {
+			*field = (*field)[:len(*field)-1]
+		}
+	case "alt+enter", "ctrl+j":
+		if f.Focus == logFormFields-1 {
+			f.Note += "\n"
+		}
+	case "tab":
+		f.Focus = (f.Focus + 1) % logFormFields
+	case "shift+tab":
+		f.Focus = (f.Focus + logFormFields - 1) % logFormFields
+	default:
+		runes := []rune(msg.String())
+		if len(runes) == 1 {
+			*f.field() += string(runes[0])
+		}
+	}
+	return m, nil
+}
+
+// field returns the form input that has focus.
+func (f *LogEdit) field() *string {
+	switch f.Focus {
+	case 0:
+		return &f.Start
+	case 1:
+		return &f.Stop
+	case 2:
+		return &f.Tag
+	case 3:
+		return &f.Project
+	}
+	return &f.Note
+}
+
+// parseLogTime reads a time entered as "YYYY-MM-DD HH:MM", or as "HH:MM" on
+// the day of was. A time left as was shows keeps its seconds.
+func parseLogTime(input string, was time.Time) (time.Time, error) {
+	input = strings.TrimSpace(input)
+	was = was.Local()
+	if input == was.Format(logTimeLayout) {
+		return was, nil
+	}
+	if t, err := time.ParseInLocation("15:04", input, time.Local); err == nil {
+		return time.Date(was.Year(), was.Month(), was.Day(), t.Hour(), t.Minute(), 0, 0, time.Local), nil
+	}
+	t, err := time.ParseInLocation(logTimeLayout, input, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("enter times as HH:MM or YYYY-MM-DD HH:MM")
+	}
+	return t, nil
+}
+
+func (m *Model) saveLogEdit() error {
+	f := m.LogEdit
+	edited := f.Log.Log
+	now := time.Now()
+	startWas, stopWas := edited.StartedAt, edited.StoppedAt
+	if edited.ID == 0 {
+		startWas, stopWas = now, now
+	}
+	var err error
+	if edited.StartedAt, err = parseLogTime(f.Start, startWas); err != nil {
+		return err
+	}
+	if edited.StoppedAt, err = parseLogTime(f.Stop, stopWas); err != nil {
+		return err
+	}
+	edited.Tags = timelog.ParseTags(f.Tag)
+	edited.Note = strings.TrimSpace(f.Note)
+
+	projects := make([]project.Project, len(m.Projects))
+	for i, p := range m.Projects {
+		projects[i] = *p
+	}
+	p, err := matchProject(projects, strings.TrimSpace(f.Project))
+	if err != nil {
+		return err
+	}
+	edited.ProjectID = p.ID
+
+	if edited.ID == 0 {
+		var added *project.Project
+		var events []hooks.Payload
+		err := m.store.WithTx(func(tx project.Store) error {
+			var err error
+			var log timelog.TimeLog
+			if added, log, err = tracker.AddLog(tx, edited, now); err != nil {
+				return err
+			}
+			events = logEvents(added, []timelog.TimeLog{log})
+			return m.events.queue(tx, events)
+		})
+		if err != nil {
+			return err
+		}
+		m.events.fire(events...)
+		return m.reloadLogs(added)
+	}
+
+	var result tracker.Edited
+	var events []hooks.Payload
+	err = m.store.WithTx(func(tx project.Store) error {
+		var err error
+		if result, err = tracker.EditLog(tx, edited, now); err != nil {
+			return err
+		}
+		for _, p := range result.Projects {
+			if p.ID == result.Log.ProjectID {
+				events = logEvents(p, []timelog.TimeLog{result.Log})
+			}
+		}
+		return m.events.queue(tx, events)
+	})
+	if err != nil {
+		return err
+	}
+	m.events.fire(events...)
+	return m.reloadLogs(result.Projects...)
+}
+
+func (m *Model) deleteSelectedLog() error {
+	lp := m.selectedLog()
+	if lp == nil {
+		return nil
+	}
+	var p *project.Project
+	var events []hooks.Payload
+	err := m.store.WithTx(func(tx project.Store) error {
+		var err error
+		if p, err = tracker.DeleteLog(tx, lp.Log.ID); err != nil {
+			return err
+		}
+		events = deletedLogEvents(p, lp.Log)
+		return m.events.queue(tx, events)
+	})
+	if err != nil {
+		return err
+	}
+	m.events.fire(events...)
+	return m.reloadLogs(p)
+}
+
+// reloadLogs brings the log viewer and the given projects, with their
+// elapsed time and logs, up to date after their logs changed. The viewer
+// keeps its filter.
+func (m *Model) reloadLogs(changed ...*project.Project) error {
+	for _, p := range changed {
+		m.syncProject(p)
+		logs, err := m.store.GetLogsByProject(p.ID)
+		if err != nil {
+			return err
+		}
+		m.TimeLogs[p.ID] = logs
+	}
+	allLogs, err := m.store.FindLogs(m.LogQuery.Filter)
+	if err != nil {
+		return err
+	}
+	m.AllLogs = allLogs
+	if m.LogViewScroll >= len(m.AllLogs) {
+		m.LogViewScroll = max(len(m.AllLogs)-1, 0)
+	}
+	return nil
+}
+
+// logFormTitle describes the log being edited for the form's title.
+func logFormTitle(l timelog.TimeLog) string {
+	if l.ID == 0 {
+		return "Add Log"
+	}
+	return fmt.Sprintf("Edit Log · %s, %s", l.StartedAt.Local().Format("Jan 02 15:04"), formatDuration(l.Duration))
+}
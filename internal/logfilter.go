@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"timer_tui/internal/project"
+	"timer_tui/internal/timelog"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// logQueryFields is the number of inputs on the log filter bar: project,
+// tags, range and search.
+const logQueryFields = 4
+
+// logRanges are the date ranges Left and Right cycle through on the filter
+// bar. Any other range is typed as YYYY-MM-DD..YYYY-MM-DD.
+var logRanges = []string{"", "today", "this week", "last month"}
+
+// LogQuery is the log viewer's filter: its inputs as typed, with the
+// project by name, and the filter they make.
+type LogQuery struct {
+	Project, Tags, Range, Search string
+	Focus                        int
+	Filter                       project.LogFilter
+}
+
+// Active reports whether the query narrows the logs at all.
+func (q LogQuery) Active() bool {
+	return q.Project != "" || q.Tags != "" || q.Range != "" || q.Search != ""
+}
+
+// openLogQuery opens the filter bar on the current query, with the input
+// at focus to type in.
+func (m *Model) openLogQuery(focus int) {
+	q := m.LogQuery
+	q.Focus = focus
+	m.LogQueryEdit = &q
+}
+
+func (m *Model) handleLogQueryInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	q := m.LogQueryEdit
+	m.Err = nil
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		m.LogQueryEdit = nil
+	case "enter":
+		m.Err = m.applyLogQuery(*q)
+		if m.Err == nil {
+			m.LogQueryEdit = nil
+		}
+	case "tab":
+		q.Focus = (q.Focus + 1) % logQueryFields
+	case "shift+tab":
+		q.Focus = (q.Focus + logQueryFields - 1) % logQueryFields
+	case "left", "right":
+		if q.Focus != 2 {
+			break
+		}
+		step := 1
+		if msg.String() == "left" {
+			step = len(logRanges) - 1
+		}
+		// A typed range counts as none.
+		i := step % len(logRanges)
+		for j, r := range logRanges {
+			if strings.EqualFold(q.Range, r) {
+				i = (j + step) % len(logRanges)
+			}
+		}
+		q.Range = logRanges[i]
+	case "backspace":
+		field := q.field()
+		if len(*field) > 0 {
+			*field = (*field)[:len(*field)-1]
+		}
+	default:
+		runes := []rune(msg.String())
+		if len(runes) == 1 {
+			*q.field() += string(runes[0])
+		}
+	}
+	return m, nil
+}
+
+// field returns the filter input that has focus.
+func (q *LogQuery) field() *string {
+	switch q.Focus {
+	case 0:
+		return &q.Project
+	case 1:
+		return &q.Tags
+	case 2:
+		return &q.Range
+	}
+	return &q.Search
+}
+
+// applyLogQuery builds the filter for q and shows the logs it matches.
+func (m *Model) applyLogQuery(q LogQuery) error {
+	q.Filter = project.LogFilter{
+		Tags:   timelog.ParseTags(q.Tags),
+		Search: strings.TrimSpace(q.Search),
+	}
+	if ref := strings.TrimSpace(q.Project); ref != "" {
+		projects := make([]project.Project, len(m.Projects))
+		for i, p := range m.Projects {
+			projects[i] = *p
+		}
+		p, err := matchProject(projects, ref)
+		if err != nil {
+			return err
+		}
+		q.Filter.ProjectID = p.ID
+	}
+	var err error
+	if q.Filter.From, q.Filter.To, err = parseLogRange(q.Range, time.Now()); err != nil {
+		return err
+	}
+
+	logs, err := m.store.FindLogs(q.Filter)
+	if err != nil {
+		return err
+	}
+	m.LogQuery = q
+	m.AllLogs = logs
+	m.LogViewScroll = 0
+	return nil
+}
+
+// parseLogRange reads a range from the filter bar as the start and end of
+// the days it covers: one of logRanges, a single day, or two days joined by
+// "..", either of which may be left out.
+func parseLogRange(s string, now time.Time) (from, to time.Time, err error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return time.Time{}, time.Time{}, nil
+	case "today":
+		return today, today.AddDate(0, 0, 1), nil
+	case "this week":
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return monday, monday.AddDate(0, 0, 7), nil
+	case "last month":
+		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return first.AddDate(0, -1, 0), first, nil
+	}
+
+	start, end, found := strings.Cut(strings.TrimSpace(s), "..")
+	if !found {
+		end = start
+	}
+	if from, err = parseDay(start, now.Location()); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to, err = parseDay(end, now.Location()); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the range %q ends before it starts", s)
+	}
+	return from, to, nil
+}
+
+// parseDay reads a YYYY-MM-DD date; a blank one is the zero time.
+func parseDay(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("enter a range as today, this week, last month or YYYY-MM-DD..YYYY-MM-DD")
+	}
+	return d, nil
+}
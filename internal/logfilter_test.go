@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLogRange(t *testing.T) {
+	// A Thursday.
+	now := time.Date(2024, 5, 16, 15, 30, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		in       string
+		from, to time.Time
+	}{
+		{"", time.Time{}, time.Time{}},
+		{"today", day(5, 16), day(5, 17)},
+		{" This Week ", day(5, 13), day(5, 20)},
+		{"last month", day(4, 1), day(5, 1)},
+		{"2024-05-01", day(5, 1), day(5, 2)},
+		{"2024-05-01..2024-05-03", day(5, 1), day(5, 4)},
+		{"2024-05-01..", day(5, 1), time.Time{}},
+		{"..2024-05-03", time.Time{}, day(5, 4)},
+	} {
+		from, to, err := parseLogRange(tc.in, now)
+		if err != nil || !from.Equal(tc.from) || !to.Equal(tc.to) {
+			t.Errorf("parseLogRange(%q) = %s, %s, %v; want %s, %s", tc.in, from, to, err, tc.from, tc.to)
+		}
+	}
+
+	for _, in := range []string{"yesterday", "2024-05-03..2024-05-01", "2024-13-01"} {
+		if _, _, err := parseLogRange(in, now); err == nil {
+			t.Errorf("parseLogRange(%q) succeeded, want error", in)
+		}
+	}
+}
@@ -1,19 +1,36 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"timer_tui/internal/hooks"
+	"timer_tui/internal/idle"
+	"timer_tui/internal/notify"
+	"timer_tui/internal/pomodoro"
 	"timer_tui/internal/project"
 	"timer_tui/internal/timelog"
 	"timer_tui/internal/timer"
+	"timer_tui/internal/tracker"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 type MsgTick struct{}
 
+// formFields is the number of inputs on the add/edit project form: name,
+// duration, pomodoro settings, notifications, notification command and
+// auto-stop.
+const formFields = 6
+
+// sessionHeartbeat is how often running sessions are confirmed in the
+// database, bounding how much of a crash gap is unaccounted for.
+const sessionHeartbeat = 30 * time.Second
+
 type Model struct {
 	Projects       []*project.Project
 	SelectedIndex  int
@@ -22,37 +39,85 @@ type Model struct {
 	EditingProject *project.Project
 	NewProjectName string
 	NewProjectTime string
-	InputFocus     int
-	Err            error
-	Timers         map[int64]*timer.Timer
-	repo           *project.Repository
+	// NewProjectPomodoro is the pomodoro field of the add/edit form, in the
+	// form pomodoro.Parse accepts; empty means a plain countdown.
+	NewProjectPomodoro string
+	// NewProjectNotify and NewProjectNotifyCommand are the notification
+	// fields, in the form notify.Parse accepts and as a shell command.
+	NewProjectNotify        string
+	NewProjectNotifyCommand string
+	InputFocus              int
+	Err                     error
+	Timers                  map[int64]*timer.Timer
+	store                   project.Store
+	notifier                notify.Notifier
+	events                  Events
 
-	// Session tracking for time logs
-	SessionStarts map[int64]time.Time // tracks when each project's current session started
+	// NewProjectAutoStop is the auto-stop field, in the form parseAutoStop
+	// accepts; empty leaves the timer running into overtime.
+	NewProjectAutoStop string
 
-	// Tag input state (shown after stopping a timer)
+	// Tag input state (shown after stopping a timer), with an optional
+	// note of several lines that NoteFocus moves typing to
 	ShowTagInput bool
 	TagInput     string
+	NoteInput    string
+	NoteFocus    bool
 	PendingLog   *timelog.TimeLog // the log entry waiting for a tag
 
+	// Suggestions for the tag being typed at the tag prompt
+	TagCompletion TagCompletion
+
 	// Time logs per project
 	TimeLogs map[int64][]timelog.TimeLog
 
-	// All-logs viewer state
-	ShowLogView   bool
-	LogViewScroll int
-	AllLogs       []project.LogWithProject
-}
+	// All-logs viewer state. LogViewScroll is the selected row, which can
+	// be opened in LogEdit or deleted after confirming. AllLogs are the logs
+	// LogQuery matches; LogQueryEdit is the filter bar while it is open.
+	ShowLogView      bool
+	LogViewScroll    int
+	AllLogs          []project.LogWithProject
+	LogEdit          *LogEdit
+	ConfirmLogDelete bool
+	LogQuery         LogQuery
+	LogQueryEdit     *LogQuery
 
-func NewModel() (*Model, error) {
-	repo, err := project.NewRepository()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
+	// Crash recovery state: sessions a previous process left running
+	Recoveries    []timelog.ActiveSession
+	RecoveryTrim  bool
+	RecoveryInput string
+	lastHeartbeat time.Time
+
+	// owner identifies this model in the sessions it runs. owned are the
+	// projects it started or resumed, which it keeps alive and stops on
+	// Close. followed are those another TUI runs, which are shown but left
+	// to it.
+	owner    string
+	owned    map[int64]bool
+	followed map[int64]bool
+
+	// dataVersion is the store's data version when it was last checked for
+	// changes made by other instances.
+	dataVersion int64
+
+	// Idle detection state: the time away the user is asked about when they
+	// come back, and the tag input for splitting it into its own log
+	IdleGap      *IdleGap
+	IdleSplit    bool
+	IdleTagInput string
+	idle         *idle.Config
+	lastInput    time.Time
+	awaySince    time.Time     // when the user went away; zero while present
+	idleSample   time.Duration // idle time last reported by the idle command
+	idleSampled  time.Time     // when it was reported
+	idlePolling  bool
+}
 
-	projectList, err := repo.GetAll()
+// NewModel loads the model's state from store. The model takes ownership of
+// store and closes it in Close.
+func NewModel(store project.Store) (*Model, error) {
+	projectList, err := store.GetAll()
 	if err != nil {
-		repo.Close()
 		return nil, fmt.Errorf("failed to load projects: %w", err)
 	}
 
@@ -61,23 +126,101 @@ func NewModel() (*Model, error) {
 		projects[i] = &projectList[i]
 	}
 
+	sessions, err := store.GetActiveSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load active sessions: %w", err)
+	}
+	active := make(map[int64]timelog.ActiveSession)
+	for _, s := range sessions {
+		active[s.ProjectID] = s
+	}
+
+	owner := newOwner()
+	owned := make(map[int64]bool)
+	followed := make(map[int64]bool)
 	timers := make(map[int64]*timer.Timer)
-	sessionStarts := make(map[int64]time.Time)
-	for i := range projects {
-		timers[projects[i].ID] = timer.New()
-		if projects[i].Running {
-			timers[projects[i].ID].Start()
-			sessionStarts[projects[i].ID] = time.Now()
+	var recoveries []timelog.ActiveSession
+	for _, p := range projects {
+		t := timer.New()
+		t.SetElapsed(p.Elapsed)
+		if s, ok := active[p.ID]; ok && p.Running && s.Owner != "" && time.Since(s.LastSeenAt) < 2*sessionHeartbeat {
+			// Another instance is running this session and keeping it
+			// alive; follow it rather than recovering it.
+			delete(active, p.ID)
+			followed[p.ID] = true
+			t.StartAt(p.StartedAt)
+			p.Elapsed = t.Elapsed()
+		} else if ok {
+			delete(active, p.ID)
+			if p.Running && s.Owner != "" {
+				// The TUI running the session died mid-session; hold the
+				// project until the user decides how much of the gap to
+				// credit.
+				recoveries = append(recoveries, s)
+				p.Running = false
+			} else if p.Running {
+				// Started from the command line or the HTTP API, which
+				// leave the session running on purpose; take it over.
+				if err := store.TouchSession(p.ID, owner, time.Now()); err != nil {
+					return nil, fmt.Errorf("failed to resume %s: %w", p.Name, err)
+				}
+				owned[p.ID] = true
+				t.StartAt(p.StartedAt)
+				p.Elapsed = t.Elapsed()
+			} else {
+				// Stopped but never logged; log it up to when it was last seen.
+				err := store.WithTx(func(tx project.Store) error {
+					if err := tx.CreateLog(&timelog.TimeLog{
+						ProjectID: p.ID,
+						StartedAt: s.StartedAt,
+						StoppedAt: s.LastSeenAt,
+						Duration:  s.LastSeenAt.Sub(s.StartedAt),
+					}); err != nil {
+						return err
+					}
+					return tx.EndSession(p.ID)
+				})
+				if err != nil {
+					return nil, fmt.Errorf("failed to log the last session of %s: %w", p.Name, err)
+				}
+			}
+		} else if p.Running && p.Pomodoro != nil && p.Phase != pomodoro.Work && p.Phase != "" {
+			// A pomodoro break has no session; the first tick moves it on
+			// if it ended while the app was closed.
+			owned[p.ID] = true
+		} else if p.Running {
+			// Resume from the persisted start so time spent while the app
+			// was not running is still counted.
+			if p.StartedAt.IsZero() {
+				p.StartedAt = time.Now()
+			}
+			t.StartAt(p.StartedAt)
+			p.Elapsed = t.Elapsed()
+			if err := store.StartSession(p.ID, p.StartedAt, ""); err != nil {
+				return nil, fmt.Errorf("failed to resume %s: %w", p.Name, err)
+			}
+			if err := store.TouchSession(p.ID, owner, time.Now()); err != nil {
+				return nil, fmt.Errorf("failed to resume %s: %w", p.Name, err)
+			}
+			owned[p.ID] = true
+		}
+		timers[p.ID] = t
+	}
+	// Sessions whose project no longer exists cannot be logged.
+	for id := range active {
+		if err := store.EndSession(id); err != nil {
+			return nil, fmt.Errorf("failed to end an orphaned session: %w", err)
 		}
 	}
 
 	// Load time logs for all projects
 	timeLogs := make(map[int64][]timelog.TimeLog)
 	for _, p := range projects {
-		logs, err := repo.GetLogsByProject(p.ID)
-		if err == nil {
-			timeLogs[p.ID] = logs
+		logs, err := store.GetLogsByProject(p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load time logs: %w", err)
 		}
+		timeLogs[p.ID] = logs
 	}
 
 	m := &Model{
@@ -86,10 +229,17 @@ func NewModel() (*Model, error) {
 		ShowAddForm:   false,
 		ShowEditForm:  false,
 		Timers:        timers,
-		repo:          repo,
-		SessionStarts: sessionStarts,
+		store:         store,
 		TimeLogs:      timeLogs,
+		Recoveries:    recoveries,
+		notifier:      notify.Terminal{Out: os.Stdout},
+		lastHeartbeat: time.Now(),
+		owner:         owner,
+		owned:         owned,
+		followed:      followed,
+		lastInput:     time.Now(),
 	}
+	m.dataVersion, _ = store.DataVersion()
 
 	return m, nil
 }
@@ -101,12 +251,52 @@ func (m *Model) Init() tea.Cmd {
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case MsgTick:
+		if v, err := m.store.DataVersion(); err == nil && v != m.dataVersion {
+			m.dataVersion = v
+			if err := m.refresh(); err != nil {
+				m.Err = err
+			}
+		}
+
+		now := time.Now()
+		heartbeat := now.Sub(m.lastHeartbeat) >= sessionHeartbeat
 		for _, p := range m.Projects {
+			before := p.Elapsed
+			// The TUI running a followed session moves it on and stops it.
+			mine := !m.followed[p.ID]
+			if mine && p.Running && p.Pomodoro != nil && p.PhaseEnd().Before(now) {
+				if err := m.advancePomodoro(p.ID, now); err != nil {
+					m.Err = err
+				}
+			}
 			t := m.Timers[p.ID]
 			if t.Running() {
-				p.Elapsed = t.Elapsed()
+				p.Elapsed = t.ElapsedAt(now)
+				if heartbeat && m.owned[p.ID] {
+					m.store.TouchSession(p.ID, m.owner, now)
+				}
+			}
+			m.budgetEvents(p, before, now)
+			if mine && p.AutoStop != nil && t.Running() && t.Base() < p.MaxTime && p.Elapsed >= p.MaxTime {
+				if err := m.autoStop(p.ID, now); err != nil {
+					m.Err = err
+				}
 			}
 		}
+		if heartbeat {
+			m.lastHeartbeat = now
+		}
+		return m, m.checkIdle(now)
+	case MsgIdleSample:
+		m.idlePolling = false
+		if msg.Err != nil {
+			m.Err = msg.Err
+		} else {
+			m.idleSample, m.idleSampled = msg.Idle, msg.At
+		}
+		return m, nil
+	case MsgControl:
+		msg.Reply <- m.handleControl(msg.Request)
 		return m, nil
 	case tea.KeyMsg:
 		return m.handleKeyMsg(msg)
@@ -121,6 +311,14 @@ func (m *Model) View() string {
 		return m.tagInputView()
 	}
 
+	if len(m.Recoveries) > 0 {
+		return m.recoveryView()
+	}
+
+	if m.IdleGap != nil {
+		return m.idleView()
+	}
+
 	if m.ShowLogView {
 		return m.allLogsView()
 	}
@@ -140,6 +338,30 @@ func (m *Model) View() string {
 	return m.mainView()
 }
 
+// SetNotifier replaces the notifier, which writes to the terminal by default.
+func (m *Model) SetNotifier(n notify.Notifier) {
+	m.notifier = n
+}
+
+// SetEvents sets where timer events are sent besides the store. By default
+// they go nowhere.
+func (m *Model) SetEvents(e Events) {
+	m.events = e
+}
+
+// budgetEvents sends the notifications due as p's elapsed time moved on
+// from before, and runs the complete hook if the budget ran out.
+func (m *Model) budgetEvents(p *project.Project, before time.Duration, now time.Time) {
+	for _, n := range p.Notify.Due(p.Name, p.MaxTime, before, p.Elapsed) {
+		if err := m.notifier.Notify(p.Notify, n); err != nil {
+			m.Err = err
+		}
+	}
+	if !p.OpenEnded() && before < p.MaxTime && p.Elapsed >= p.MaxTime {
+		m.events.fire(hooks.ProjectEvent(hooks.Complete, m.storedProject(p), now))
+	}
+}
+
 func (m *Model) SelectedProject() *project.Project {
 	if m.SelectedIndex >= 0 && m.SelectedIndex < len(m.Projects) {
 		return m.Projects[m.SelectedIndex]
@@ -147,6 +369,15 @@ func (m *Model) SelectedProject() *project.Project {
 	return nil
 }
 
+func (m *Model) projectByID(id int64) *project.Project {
+	for _, p := range m.Projects {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
 func (m *Model) SelectedTimer() *timer.Timer {
 	p := m.SelectedProject()
 	if p == nil {
@@ -155,11 +386,18 @@ func (m *Model) SelectedTimer() *timer.Timer {
 	return m.Timers[p.ID]
 }
 
-func (m *Model) AddProject(name string, maxTime time.Duration) error {
-	p, err := m.repo.Create(name, maxTime)
+// AddProject creates a project with draft's name, budget and settings.
+func (m *Model) AddProject(draft project.Project) error {
+	p, err := m.store.Create(draft.Name, draft.MaxTime)
 	if err != nil {
 		return err
 	}
+	p.Pomodoro = draft.Pomodoro
+	p.Notify = draft.Notify
+	p.AutoStop = draft.AutoStop
+	if err := m.store.Update(p); err != nil {
+		return err
+	}
 	m.Timers[p.ID] = timer.New()
 	m.TimeLogs[p.ID] = nil
 	m.Projects = append(m.Projects, p)
@@ -168,7 +406,7 @@ func (m *Model) AddProject(name string, maxTime time.Duration) error {
 }
 
 func (m *Model) UpdateProject(p *project.Project) error {
-	if err := m.repo.Update(p); err != nil {
+	if err := m.saveProject(p); err != nil {
 		return err
 	}
 	for i, proj := range m.Projects {
@@ -180,12 +418,43 @@ func (m *Model) UpdateProject(p *project.Project) error {
 	return nil
 }
 
+// saveProject persists p. If another instance saved the project since it
+// was loaded, nothing is written and p is reloaded instead.
+func (m *Model) saveProject(p *project.Project) error {
+	stored := m.storedProject(p)
+	err := m.store.Update(stored)
+	if errors.Is(err, project.ErrConflict) {
+		m.reloadProject(p.ID)
+		return fmt.Errorf("%s was changed by another instance and has been reloaded; save again to overwrite it", stored.Name)
+	}
+	p.Revision = stored.Revision
+	return err
+}
+
+// storedProject returns p as it should be persisted. A running project is
+// stored with the time banked before its session started, so the live value
+// can be rebuilt from StartedAt after a restart.
+func (m *Model) storedProject(p *project.Project) *project.Project {
+	stored := *p
+	if t, ok := m.Timers[p.ID]; ok && t.Running() {
+		stored.Elapsed = t.Base()
+		stored.StartedAt = t.StartedAt()
+	}
+	return &stored
+}
+
 func (m *Model) DeleteProject(id int64) error {
-	if err := m.repo.Delete(id); err != nil {
+	// Logs and any active session go with the project via ON DELETE CASCADE.
+	if err := m.store.Delete(id); err != nil {
 		return err
 	}
+	m.removeProject(id)
+	return nil
+}
+
+// removeProject drops a deleted project from the model.
+func (m *Model) removeProject(id int64) {
 	delete(m.Timers, id)
-	delete(m.SessionStarts, id)
 	delete(m.TimeLogs, id)
 	for i, p := range m.Projects {
 		if p.ID == id {
@@ -196,68 +465,359 @@ func (m *Model) DeleteProject(id int64) error {
 	if m.SelectedIndex >= len(m.Projects) {
 		m.SelectedIndex = len(m.Projects) - 1
 	}
-	return nil
 }
 
-func (m *Model) StopAllTimers() {
+// refresh picks up what other instances changed in the store: projects they
+// added or deleted, and those whose revision moved on. A project being
+// edited or waiting at the tag prompt keeps its local state; saving it then
+// reports the conflict.
+func (m *Model) refresh() error {
+	if len(m.Recoveries) > 0 {
+		return nil
+	}
+	stored, err := m.store.GetAll()
+	if err != nil {
+		return err
+	}
+
+	busy := func(id int64) bool {
+		return m.PendingLog != nil && m.PendingLog.ProjectID == id ||
+			m.EditingProject != nil && m.EditingProject.ID == id
+	}
+
+	byID := make(map[int64]bool, len(stored))
+	for i := range stored {
+		s := &stored[i]
+		byID[s.ID] = true
+		p := m.projectByID(s.ID)
+		if p == nil {
+			m.Projects = append(m.Projects, s)
+			m.Timers[s.ID] = timer.New()
+		} else if p.Revision == s.Revision || busy(s.ID) {
+			continue
+		}
+		m.syncProject(s)
+		if logs, err := m.store.GetLogsByProject(s.ID); err == nil {
+			m.TimeLogs[s.ID] = logs
+		}
+	}
+
+	// Sessions another TUI started or took over are followed from now on.
+	sessions, err := m.store.GetActiveSessions()
+	if err != nil {
+		return err
+	}
+	m.followed = make(map[int64]bool)
+	for _, s := range sessions {
+		if s.Owner != "" && s.Owner != m.owner {
+			m.followed[s.ProjectID] = true
+			delete(m.owned, s.ProjectID)
+		}
+	}
+
+	var gone []int64
 	for _, p := range m.Projects {
-		t := m.Timers[p.ID]
-		if t.Running() {
-			t.Stop()
-			p.Elapsed = t.Elapsed()
-			p.Running = false
-			m.repo.Update(p)
-			// Note: when stopping all timers due to starting another,
-			// we silently log without a tag prompt
-			if startedAt, ok := m.SessionStarts[p.ID]; ok {
-				stoppedAt := time.Now()
-				duration := stoppedAt.Sub(startedAt)
-				log := &timelog.TimeLog{
-					ProjectID: p.ID,
-					StartedAt: startedAt,
-					StoppedAt: stoppedAt,
-					Duration:  duration,
-					Tag:       "",
-				}
-				m.repo.CreateLog(log)
-				m.TimeLogs[p.ID] = append([]timelog.TimeLog{*log}, m.TimeLogs[p.ID]...)
-				delete(m.SessionStarts, p.ID)
+		if !byID[p.ID] && !busy(p.ID) {
+			gone = append(gone, p.ID)
+		}
+	}
+	for _, id := range gone {
+		m.removeProject(id)
+	}
+
+	if m.ShowLogView && m.LogEdit == nil {
+		if allLogs, err := m.store.FindLogs(m.LogQuery.Filter); err == nil {
+			m.AllLogs = allLogs
+		}
+	}
+	return nil
+}
+
+// newOwner returns an identifier for a model to claim sessions with, unique
+// to its process and when it started.
+func newOwner() string {
+	return fmt.Sprintf("tui:%d:%d", os.Getpid(), time.Now().UnixNano())
+}
+
+// StopOwnedTimers stops the timers this model started or resumed. Sessions
+// other instances run, and those waiting to be recovered, are left running.
+func (m *Model) StopOwnedTimers() error {
+	now := time.Now()
+	var stopped []tracker.Stopped
+	err := m.store.WithTx(func(tx project.Store) error {
+		sessions, err := tx.GetActiveSessions()
+		if err != nil {
+			return err
+		}
+		owners := make(map[int64]string, len(sessions))
+		for _, s := range sessions {
+			owners[s.ProjectID] = s.Owner
+		}
+		for _, p := range m.Projects {
+			if !m.owned[p.ID] || owners[p.ID] != "" && owners[p.ID] != m.owner {
+				continue
+			}
+			s, err := tracker.Stop(tx, p.ID, now, "")
+			if errors.Is(err, tracker.ErrNotRunning) {
+				continue
 			}
+			if err != nil {
+				return fmt.Errorf("stop %s: %w", p.Name, err)
+			}
+			stopped = append(stopped, s)
 		}
+		return m.events.queue(tx, stoppedEvents(stopped, now))
+	})
+	if err != nil {
+		return err
+	}
+	m.applyStopped(stopped)
+	m.events.fire(stoppedEvents(stopped, now)...)
+	return nil
+}
+
+// syncProject updates the in-memory project and its timer from the state the
+// tracker stored for it.
+func (m *Model) syncProject(stored *project.Project) {
+	p := m.projectByID(stored.ID)
+	if p == nil {
+		return
+	}
+	*p = *stored
+	t := m.Timers[p.ID]
+	t.Reset()
+	t.SetElapsed(stored.Elapsed)
+	// A pomodoro break keeps the project running without a session.
+	if stored.Running && !stored.StartedAt.IsZero() {
+		t.StartAt(stored.StartedAt)
+		p.Elapsed = t.Elapsed()
 	}
 }
 
-func (m *Model) Close() error {
-	for _, p := range m.Projects {
-		t := m.Timers[p.ID]
-		if t.Running() {
-			t.Stop()
-			p.Elapsed = t.Elapsed()
-			p.Running = false
-			// Log any running sessions on close
-			if startedAt, ok := m.SessionStarts[p.ID]; ok {
-				stoppedAt := time.Now()
-				duration := stoppedAt.Sub(startedAt)
-				log := &timelog.TimeLog{
-					ProjectID: p.ID,
-					StartedAt: startedAt,
-					StoppedAt: stoppedAt,
-					Duration:  duration,
-					Tag:       "",
-				}
-				m.repo.CreateLog(log)
+// reloadProject resyncs a project from the store after a failed write, so the
+// UI does not show state that was never saved.
+func (m *Model) reloadProject(id int64) {
+	if stored, err := m.store.GetByID(id); err == nil {
+		m.syncProject(stored)
+	}
+}
+
+func (m *Model) applyStopped(stopped []tracker.Stopped) {
+	for _, s := range stopped {
+		m.syncProject(s.Project)
+		m.prependLogs(s.Project.ID, s.Logs)
+	}
+}
+
+// prependLogs adds logs, oldest first, to the front of the project's
+// newest-first log list.
+func (m *Model) prependLogs(id int64, logs []timelog.TimeLog) {
+	for _, l := range logs {
+		m.TimeLogs[id] = append([]timelog.TimeLog{l}, m.TimeLogs[id]...)
+	}
+}
+
+// advancePomodoro moves a running pomodoro on to its current phase, logging
+// any work phase that finished.
+func (m *Model) advancePomodoro(id int64, now time.Time) error {
+	var advanced *project.Project
+	var logs []timelog.TimeLog
+	err := m.store.WithTx(func(tx project.Store) error {
+		var err error
+		if advanced, logs, err = tracker.Advance(tx, id, now); err != nil {
+			return err
+		}
+		// A work phase starting anew starts a new session.
+		if m.owned[id] {
+			if err := tx.TouchSession(id, m.owner, now); err != nil {
+				return err
 			}
-			m.repo.Update(p)
 		}
+		return m.events.queue(tx, logEvents(advanced, logs))
+	})
+	if err != nil {
+		return err
+	}
+	m.syncProject(advanced)
+	m.prependLogs(id, logs)
+	m.events.fire(logEvents(advanced, logs)...)
+	return nil
+}
+
+// startProject starts the project's timer, stopping and logging whichever
+// other timer was running.
+func (m *Model) startProject(id int64, tag string) ([]tracker.Stopped, error) {
+	now := time.Now()
+	var started *project.Project
+	var stopped []tracker.Stopped
+	var events []hooks.Payload
+	err := m.store.WithTx(func(tx project.Store) error {
+		var err error
+		if started, stopped, err = tracker.Start(tx, id, now, tag); err != nil {
+			return err
+		}
+		if err := tx.TouchSession(id, m.owner, now); err != nil {
+			return err
+		}
+		payload := hooks.ProjectEvent(hooks.Start, started, now)
+		payload.Tag = tag
+		events = append(stoppedEvents(stopped, now), payload)
+		return m.events.queue(tx, events)
+	})
+	if err != nil {
+		m.reloadProject(id)
+		return nil, err
 	}
-	return m.repo.Close()
+	m.applyStopped(stopped)
+	m.events.fire(events...)
+	m.syncProject(started)
+	m.owned[id] = true
+	return stopped, nil
+}
+
+// stopProject stops the project's timer at now and logs the session with
+// tag and note.
+func (m *Model) stopProject(id int64, now time.Time, tag, note string) (tracker.Stopped, error) {
+	var stopped tracker.Stopped
+	err := m.store.WithTx(func(tx project.Store) error {
+		var err error
+		if stopped, err = tracker.Stop(tx, id, now, tag); err != nil {
+			return err
+		}
+		if err := tracker.Note(tx, &stopped, note); err != nil {
+			return err
+		}
+		return m.events.queue(tx, stoppedEvents([]tracker.Stopped{stopped}, now))
+	})
+	if err != nil {
+		m.reloadProject(id)
+		return stopped, err
+	}
+	m.applyStopped([]tracker.Stopped{stopped})
+	m.events.fire(stoppedEvents([]tracker.Stopped{stopped}, now)...)
+	return stopped, nil
+}
+
+// autoStop stops the project's timer if its budget ran out and it is set to
+// stop then, starting its follow-up project.
+func (m *Model) autoStop(id int64, now time.Time) error {
+	var auto *tracker.AutoStopped
+	var events []hooks.Payload
+	err := m.store.WithTx(func(tx project.Store) error {
+		var err error
+		if auto, err = tracker.AutoStop(tx, id, now); err != nil || auto == nil {
+			return err
+		}
+		events = stoppedEvents([]tracker.Stopped{auto.Stopped}, auto.At)
+		if auto.Next != nil {
+			events = append(events, hooks.ProjectEvent(hooks.Start, auto.Next, auto.At))
+			if err := tx.TouchSession(auto.Next.ID, m.owner, now); err != nil {
+				return err
+			}
+		}
+		return m.events.queue(tx, events)
+	})
+	if err != nil {
+		m.reloadProject(id)
+		return err
+	}
+	if auto == nil {
+		return nil
+	}
+	m.applyStopped([]tracker.Stopped{auto.Stopped})
+	if auto.Next != nil {
+		m.syncProject(auto.Next)
+		m.owned[auto.Next.ID] = true
+	}
+	m.events.fire(events...)
+	return nil
+}
+
+// commitPendingLog stops the session waiting at the tag prompt in the store
+// and writes its log in one transaction. It returns the stop, or nil if
+// there was nothing to commit or it failed.
+func (m *Model) commitPendingLog(tag, note string) *tracker.Stopped {
+	log := m.PendingLog
+	m.PendingLog = nil
+	m.ShowTagInput = false
+	m.TagInput = ""
+	m.NoteInput = ""
+	m.NoteFocus = false
+	m.TagCompletion = TagCompletion{}
+	if log == nil {
+		return nil
+	}
+
+	stopped, err := m.stopProject(log.ProjectID, log.StoppedAt, tag, strings.TrimSpace(note))
+	m.Err = err
+	if err != nil {
+		return nil
+	}
+	return &stopped
+}
+
+// beginStop stops the project's timer at stoppedAt and shows the tag prompt.
+// Nothing is written until the tag is entered, so the stop and its log land
+// together.
+func (m *Model) beginStop(p *project.Project, t *timer.Timer, stoppedAt time.Time) {
+	startedAt := t.StartedAt()
+	t.StopAt(stoppedAt)
+	p.Elapsed = t.Elapsed()
+	p.Running = false
+	p.StartedAt = time.Time{}
+
+	m.PendingLog = &timelog.TimeLog{
+		ProjectID: p.ID,
+		StartedAt: startedAt,
+		StoppedAt: stoppedAt,
+		Duration:  stoppedAt.Sub(startedAt),
+	}
+	m.TagInput = ""
+	m.NoteInput = ""
+	m.NoteFocus = false
+	m.ShowTagInput = true
+	m.suggestTags()
+}
+
+func (m *Model) Close() error {
+	// A session waiting at the tag prompt is logged up to when it was
+	// stopped, with what was typed so far. Sessions this model left running
+	// are logged untagged up to now.
+	var err error
+	if m.PendingLog != nil {
+		m.commitPendingLog(m.TagInput, m.NoteInput)
+		err = m.Err
+	}
+	if serr := m.StopOwnedTimers(); err == nil {
+		err = serr
+	}
+	if werr := m.events.Wait(); err == nil {
+		err = werr
+	}
+	if cerr := m.store.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
 
 func (m *Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.lastInput = time.Now()
 	if m.ShowTagInput {
 		return m.handleTagInput(msg)
 	}
 
+	if len(m.Recoveries) > 0 {
+		return m.handleRecoveryInput(msg)
+	}
+
+	if m.IdleGap == nil && m.comeBack(m.lastInput) {
+		// The key that brought the user back only opens the prompt.
+		return m, nil
+	}
+	if m.IdleGap != nil {
+		return m.handleIdleInput(msg)
+	}
+
 	if m.ShowLogView {
 		return m.handleLogViewInput(msg)
 	}
@@ -266,6 +826,7 @@ func (m *Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleFormInput(msg)
 	}
 
+	m.Err = nil
 	switch msg.String() {
 	case "ctrl+c", "q":
 		return m, tea.Quit
@@ -281,44 +842,24 @@ func (m *Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		p := m.SelectedProject()
 		if p != nil {
 			t := m.SelectedTimer()
-			if t.Running() {
-				// Stop the timer and show tag input prompt
-				t.Stop()
-				p.Elapsed = t.Elapsed()
-				p.Running = false
-				m.repo.Update(p)
-
-				stoppedAt := time.Now()
-				startedAt := stoppedAt // fallback
-				if sa, ok := m.SessionStarts[p.ID]; ok {
-					startedAt = sa
-					delete(m.SessionStarts, p.ID)
-				}
-				duration := stoppedAt.Sub(startedAt)
-
-				m.PendingLog = &timelog.TimeLog{
-					ProjectID: p.ID,
-					StartedAt: startedAt,
-					StoppedAt: stoppedAt,
-					Duration:  duration,
-					Tag:       "",
-				}
-				m.TagInput = ""
-				m.ShowTagInput = true
+			if p.Running && !t.Running() {
+				// A pomodoro break has nothing to log, so it stops at once.
+				_, m.Err = m.stopProject(p.ID, time.Now(), "", "")
+			} else if t.Running() {
+				m.beginStop(p, t, time.Now())
 			} else {
-				// Stop all other timers first (will auto-log them without tag)
-				m.StopAllTimers()
-				t.SetElapsed(p.Elapsed)
-				t.Start()
-				p.Running = true
-				m.SessionStarts[p.ID] = time.Now()
-				m.repo.Update(p)
+				// Any other running timer is stopped and logged without a tag
+				_, m.Err = m.startProject(p.ID, "")
 			}
 		}
 	case "n":
 		m.ShowAddForm = true
 		m.NewProjectName = ""
 		m.NewProjectTime = ""
+		m.NewProjectPomodoro = ""
+		m.NewProjectNotify = notify.Bell
+		m.NewProjectNotifyCommand = ""
+		m.NewProjectAutoStop = ""
 		m.InputFocus = 0
 	case "e":
 		p := m.SelectedProject()
@@ -326,27 +867,41 @@ func (m *Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.ShowEditForm = true
 			m.EditingProject = p
 			m.NewProjectName = p.Name
-			m.NewProjectTime = fmt.Sprintf("%d", int(p.MaxTime.Minutes()))
+			m.NewProjectTime = ""
+			if !p.OpenEnded() {
+				m.NewProjectTime = fmt.Sprintf("%d", int(p.MaxTime.Minutes()))
+			}
+			m.NewProjectPomodoro = ""
+			if p.Pomodoro != nil {
+				m.NewProjectPomodoro = p.Pomodoro.String()
+			}
+			m.NewProjectNotify = p.Notify.String()
+			m.NewProjectNotifyCommand = p.Notify.Command
+			m.NewProjectAutoStop = m.formatAutoStop(p.AutoStop)
 			m.InputFocus = 0
 		}
 	case "d":
 		p := m.SelectedProject()
 		if p != nil {
-			m.DeleteProject(p.ID)
+			m.Err = m.DeleteProject(p.ID)
 		}
 	case "r":
 		p := m.SelectedProject()
 		if p != nil {
-			t := m.SelectedTimer()
-			t.Reset()
-			p.Elapsed = 0
-			p.Running = false
-			delete(m.SessionStarts, p.ID)
-			m.repo.Update(p)
+			var reset *project.Project
+			m.Err = m.store.WithTx(func(tx project.Store) error {
+				var err error
+				reset, err = tracker.Reset(tx, p.ID)
+				return err
+			})
+			if m.Err == nil {
+				m.syncProject(reset)
+				m.events.fire(hooks.ProjectEvent(hooks.Reset, reset, time.Now()))
+			}
 		}
 	case "l":
 		// Open the all-logs viewer
-		allLogs, err := m.repo.GetAllLogs()
+		allLogs, err := m.store.GetAllLogs()
 		if err == nil {
 			m.AllLogs = allLogs
 		} else {
@@ -361,10 +916,42 @@ func (m *Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *Model) handleLogViewInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.LogEdit != nil {
+		return m.handleLogEditInput(msg)
+	}
+	if m.LogQueryEdit != nil {
+		return m.handleLogQueryInput(msg)
+	}
+	if m.ConfirmLogDelete {
+		m.ConfirmLogDelete = false
+		if msg.String() == "y" {
+			m.Err = m.deleteSelectedLog()
+		}
+		return m, nil
+	}
+
+	m.Err = nil
 	switch msg.String() {
 	case "ctrl+c", "q", "esc", "l":
 		m.ShowLogView = false
 		m.AllLogs = nil
+		m.LogQuery = LogQuery{}
+	case "f":
+		m.openLogQuery(0)
+	case "/":
+		m.openLogQuery(logQueryFields - 1)
+	case "x":
+		if m.LogQuery.Active() {
+			m.Err = m.applyLogQuery(LogQuery{})
+		}
+	case "e", "enter":
+		if lp := m.selectedLog(); lp != nil {
+			m.openLogEdit(*lp)
+		}
+	case "d":
+		m.ConfirmLogDelete = m.selectedLog() != nil
+	case "a":
+		m.openLogAdd()
 	case "up", "k":
 		if m.LogViewScroll > 0 {
 			m.LogViewScroll--
@@ -382,116 +969,331 @@ func (m *Model) handleLogViewInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *Model) handleTagInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	field := &m.TagInput
+	if m.NoteFocus {
+		field = &m.NoteInput
+	}
 	switch msg.String() {
 	case "ctrl+c", "esc":
-		// Save the log without a tag
-		if m.PendingLog != nil {
-			m.PendingLog.Tag = ""
-			m.repo.CreateLog(m.PendingLog)
-			m.TimeLogs[m.PendingLog.ProjectID] = append(
-				[]timelog.TimeLog{*m.PendingLog},
-				m.TimeLogs[m.PendingLog.ProjectID]...,
-			)
-			m.PendingLog = nil
-		}
-		m.ShowTagInput = false
-		m.TagInput = ""
+		// Save the log without a tag or note
+		m.commitPendingLog("", "")
 	case "enter":
-		// Save the log with the tag
-		if m.PendingLog != nil {
-			m.PendingLog.Tag = m.TagInput
-			m.repo.CreateLog(m.PendingLog)
-			m.TimeLogs[m.PendingLog.ProjectID] = append(
-				[]timelog.TimeLog{*m.PendingLog},
-				m.TimeLogs[m.PendingLog.ProjectID]...,
-			)
-			m.PendingLog = nil
-		}
-		m.ShowTagInput = false
-		m.TagInput = ""
+		// Save the log with the tag and note
+		m.commitPendingLog(m.TagInput, m.NoteInput)
+	case "tab", "shift+tab":
+		// Tab completes the tag being typed, going through the
+		// suggestions for it before moving on to the note.
+		step := 1
+		if msg.String() == "shift+tab" {
+			step = -1
+		}
+		if m.NoteFocus || !m.cycleTag(step) {
+			m.NoteFocus = !m.NoteFocus
+		}
+	case "down":
+		m.NoteFocus = true
+	case "up":
+		m.NoteFocus = false
+	case "alt+enter", "ctrl+j":
+		if m.NoteFocus {
+			m.NoteInput += "\n"
+		}
 	case "backspace":
-		if len(m.TagInput) > 0 {
-			m.TagInput = m.TagInput[:len(m.TagInput)-1]
+		if len(*field) > 0 {
+			*field = (*field)[:len(*field)-1]
+		}
+		if !m.NoteFocus {
+			m.suggestTags()
 		}
 	default:
 		runes := []rune(msg.String())
 		if len(runes) == 1 {
-			m.TagInput += string(runes[0])
+			*field += string(runes[0])
+		}
+		if !m.NoteFocus {
+			m.suggestTags()
 		}
 	}
 	return m, nil
 }
 
+func (m *Model) handleRecoveryInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.RecoveryTrim {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			m.RecoveryTrim = false
+			m.RecoveryInput = ""
+			m.Err = nil
+		case "enter":
+			end, err := parseClock(m.RecoveryInput, time.Now())
+			if err == nil && end.Before(m.Recoveries[0].StartedAt) {
+				err = fmt.Errorf("end time is before the session started")
+			}
+			if err != nil {
+				m.Err = err
+				break
+			}
+			m.resolveRecovery(end)
+		case "backspace":
+			if len(m.RecoveryInput) > 0 {
+				m.RecoveryInput = m.RecoveryInput[:len(m.RecoveryInput)-1]
+			}
+		default:
+			runes := []rune(msg.String())
+			if len(runes) == 1 && (runes[0] == ':' || (runes[0] >= '0' && runes[0] <= '9')) {
+				m.RecoveryInput += string(runes[0])
+			}
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		// Leave the sessions in place; they are offered again next launch.
+		return m, tea.Quit
+	case "f", "enter":
+		m.resolveRecovery(time.Now())
+	case "t":
+		m.RecoveryTrim = true
+		m.RecoveryInput = ""
+		m.Err = nil
+	case "x":
+		m.resolveRecovery(m.Recoveries[0].LastSeenAt)
+	}
+	return m, nil
+}
+
+// resolveRecovery ends the first recovered session at end, credits the
+// project and hands the session to the tag prompt, which stores both.
+func (m *Model) resolveRecovery(end time.Time) {
+	s := m.Recoveries[0]
+	m.Recoveries = m.Recoveries[1:]
+	m.RecoveryTrim = false
+	m.RecoveryInput = ""
+	m.Err = nil
+
+	p := m.projectByID(s.ProjectID)
+	if p == nil {
+		m.Err = m.store.EndSession(s.ProjectID)
+		return
+	}
+
+	start := p.StartedAt
+	if start.IsZero() {
+		start = s.StartedAt
+	}
+	if end.After(start) {
+		p.Elapsed += end.Sub(start)
+	}
+	p.Running = false
+	p.StartedAt = time.Time{}
+	m.Timers[p.ID].SetElapsed(p.Elapsed)
+
+	m.PendingLog = &timelog.TimeLog{
+		ProjectID: p.ID,
+		StartedAt: s.StartedAt,
+		StoppedAt: end,
+		Duration:  end.Sub(s.StartedAt),
+	}
+	m.TagInput = ""
+	m.NoteInput = ""
+	m.NoteFocus = false
+	m.ShowTagInput = true
+	m.suggestTags()
+}
+
+// parseClock interprets an HH:MM input as the latest such time at or before now.
+func parseClock(input string, now time.Time) (time.Time, error) {
+	t, err := time.ParseInLocation("15:04", input, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("enter the end time as HH:MM")
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if at.After(now) {
+		at = at.AddDate(0, 0, -1)
+	}
+	return at, nil
+}
+
 func (m *Model) handleFormInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c", "esc":
-		m.ShowAddForm = false
-		m.ShowEditForm = false
-		m.EditingProject = nil
+		m.closeForm()
 	case "enter":
-		if m.InputFocus == 0 {
-			m.InputFocus = 1
+		if m.InputFocus < formFields-1 {
+			m.InputFocus++
 		} else {
-			if m.ShowAddForm {
-				minutes := 0
-				if m.NewProjectTime != "" {
-					if v, err := strconv.Atoi(m.NewProjectTime); err == nil {
-						minutes = v
-					}
-				}
-				duration := time.Duration(minutes) * time.Minute
-				if duration <= 0 {
-					duration = 25 * time.Minute
-				}
-				m.AddProject(m.NewProjectName, duration)
-			} else if m.ShowEditForm && m.EditingProject != nil {
-				minutes := 0
-				if m.NewProjectTime != "" {
-					if v, err := strconv.Atoi(m.NewProjectTime); err == nil {
-						minutes = v
-					}
-				}
-				duration := time.Duration(minutes) * time.Minute
-				if duration <= 0 {
-					duration = 25 * time.Minute
-				}
-				m.EditingProject.Name = m.NewProjectName
-				m.EditingProject.MaxTime = duration
-				if m.EditingProject.Elapsed > duration {
-					m.EditingProject.Elapsed = duration
-				}
-				m.UpdateProject(m.EditingProject)
+			m.Err = m.submitForm()
+			if m.Err == nil {
+				m.closeForm()
 			}
-			m.ShowAddForm = false
-			m.ShowEditForm = false
-			m.EditingProject = nil
 		}
 	case "backspace":
-		if m.InputFocus == 0 {
-			if len(m.NewProjectName) > 0 {
-				m.NewProjectName = m.NewProjectName[:len(m.NewProjectName)-1]
-			}
-		} else {
-			if len(m.NewProjectTime) > 0 {
-				m.NewProjectTime = m.NewProjectTime[:len(m.NewProjectTime)-1]
-			}
+		field := m.formField()
+		if len(*field) > 0 {
+			*field = (*field)[:len(*field)-1]
 		}
 	case "tab":
-		m.InputFocus = 1 - m.InputFocus
+		m.InputFocus = (m.InputFocus + 1) % formFields
+	case "shift+tab":
+		m.InputFocus = (m.InputFocus + formFields - 1) % formFields
 	default:
-		if msg.String() == "tab" || msg.String() == "shift+tab" {
-			break
-		}
 		runes := []rune(msg.String())
 		if len(runes) == 1 {
-			if m.InputFocus == 0 {
-				m.NewProjectName += string(runes[0])
-			} else {
-				if runes[0] >= '0' && runes[0] <= '9' {
-					m.NewProjectTime += string(runes[0])
-				}
+			if m.InputFocus == 1 && (runes[0] < '0' || runes[0] > '9') {
+				break
 			}
+			*m.formField() += string(runes[0])
 		}
 	}
 	return m, nil
 }
+
+// formField returns the form input that has focus.
+func (m *Model) formField() *string {
+	switch m.InputFocus {
+	case 0:
+		return &m.NewProjectName
+	case 1:
+		return &m.NewProjectTime
+	case 2:
+		return &m.NewProjectPomodoro
+	case 3:
+		return &m.NewProjectNotify
+	case 4:
+		return &m.NewProjectNotifyCommand
+	}
+	return &m.NewProjectAutoStop
+}
+
+func (m *Model) closeForm() {
+	m.ShowAddForm = false
+	m.ShowEditForm = false
+	m.EditingProject = nil
+	m.Err = nil
+}
+
+// submitForm saves the add or edit form. A blank duration makes the project
+// open-ended. Invalid pomodoro settings keep the form open with the error
+// shown.
+func (m *Model) submitForm() error {
+	minutes := 0
+	if m.NewProjectTime != "" {
+		if v, err := strconv.Atoi(m.NewProjectTime); err == nil {
+			minutes = v
+		}
+	}
+	duration := time.Duration(minutes) * time.Minute
+
+	var pomo *pomodoro.Settings
+	if m.NewProjectPomodoro != "" {
+		settings, err := pomodoro.Parse(m.NewProjectPomodoro)
+		if err != nil {
+			return err
+		}
+		pomo = &settings
+	}
+
+	notifySettings, err := notify.Parse(m.NewProjectNotify)
+	if err != nil {
+		return err
+	}
+	notifySettings.Command = strings.TrimSpace(m.NewProjectNotifyCommand)
+
+	var self int64
+	if m.ShowEditForm && m.EditingProject != nil {
+		self = m.EditingProject.ID
+	}
+	autoStop, err := m.parseAutoStop(m.NewProjectAutoStop, self)
+	if err != nil {
+		return err
+	}
+	if autoStop != nil && duration == 0 {
+		return fmt.Errorf("auto-stop needs a duration to stop at")
+	}
+
+	if m.ShowAddForm {
+		return m.AddProject(project.Project{
+			Name:     m.NewProjectName,
+			MaxTime:  duration,
+			Pomodoro: pomo,
+			Notify:   notifySettings,
+			AutoStop: autoStop,
+		})
+	}
+	p := m.EditingProject
+	if p == nil {
+		return nil
+	}
+	if p.Running && !samePomodoro(p.Pomodoro, pomo) {
+		return fmt.Errorf("stop the timer before changing its pomodoro settings")
+	}
+	p.Name = m.NewProjectName
+	p.MaxTime = duration
+	p.Pomodoro = pomo
+	p.Notify = notifySettings
+	p.AutoStop = autoStop
+	if pomo == nil {
+		p.Phase = ""
+		p.PhaseStartedAt = time.Time{}
+		p.Cycle = 0
+	}
+	return m.UpdateProject(p)
+}
+
+// parseAutoStop reads the auto-stop field: blank or "off" for none, else
+// "on", optionally followed by the tag to log the stopped session with and
+// "> project" naming a project to start next, such as "on budget > Review".
+// self is the project being edited, which cannot follow itself.
+func (m *Model) parseAutoStop(s string, self int64) (*project.AutoStop, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "off") {
+		return nil, nil
+	}
+	if len(s) < 2 || !strings.EqualFold(s[:2], "on") || (len(s) > 2 && s[2] != ' ' && s[2] != '>') {
+		return nil, fmt.Errorf("auto-stop must be blank, or \"on\" with an optional tag and \"> project\"")
+	}
+	tag, next, hasNext := strings.Cut(s[2:], ">")
+	autoStop := &project.AutoStop{Tag: strings.TrimSpace(tag)}
+	if !hasNext {
+		return autoStop, nil
+	}
+
+	projects := make([]project.Project, len(m.Projects))
+	for i, p := range m.Projects {
+		projects[i] = *p
+	}
+	p, err := matchProject(projects, strings.TrimSpace(next))
+	if err != nil {
+		return nil, fmt.Errorf("auto-stop: %w", err)
+	}
+	if p.ID == self {
+		return nil, fmt.Errorf("auto-stop cannot start the project itself again")
+	}
+	autoStop.FollowUpID = p.ID
+	return autoStop, nil
+}
+
+// formatAutoStop writes settings back in the form parseAutoStop reads.
+func (m *Model) formatAutoStop(a *project.AutoStop) string {
+	if a == nil {
+		return ""
+	}
+	s := "on"
+	if a.Tag != "" {
+		s += " " + a.Tag
+	}
+	if next := m.projectByID(a.FollowUpID); next != nil {
+		s += " > " + next.Name
+	}
+	return s
+}
+
+func samePomodoro(a, b *pomodoro.Settings) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
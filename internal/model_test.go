@@ -0,0 +1,534 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"timer_tui/internal/hooks"
+	"timer_tui/internal/notify"
+	"timer_tui/internal/project"
+	"timer_tui/internal/timelog"
+	"timer_tui/internal/webhook"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// press sends each key to the model in turn: "enter", "tab", "ctrl+j", or
+// the runes to type.
+func press(m *Model, keys ...string) {
+	for _, k := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "tab":
+			msg = tea.KeyMsg{Type: tea.KeyTab}
+		case "ctrl+j":
+			msg = tea.KeyMsg{Type: tea.KeyCtrlJ}
+		}
+		m.Update(msg)
+	}
+}
+
+// Starting, stopping with a tag and deleting a project from the keyboard
+// land in the store.
+func TestModelStartStopTagDelete(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	press(m, "enter")
+	if stored, err := store.GetByID(p.ID); err != nil || !stored.Running {
+		t.Fatalf("after enter: running = %v, %v; want running", stored != nil && stored.Running, err)
+	}
+	if sessions, err := store.GetActiveSessions(); err != nil || len(sessions) != 1 {
+		t.Fatalf("after enter: sessions = %+v, %v; want one", sessions, err)
+	}
+
+	press(m, "enter")
+	if !m.ShowTagInput {
+		t.Fatal("stopping did not ask for a tag")
+	}
+	press(m, "r", "e", "v", "i", "e", "w", "enter")
+	stored, err := store.GetByID(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Running {
+		t.Fatal("after stop: still running")
+	}
+	logs, err := store.GetLogsByProject(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || timelog.FormatTags(logs[0].Tags) != "review" {
+		t.Fatalf("logs = %+v, want one tagged review", logs)
+	}
+	if sessions, err := store.GetActiveSessions(); err != nil || len(sessions) != 0 {
+		t.Fatalf("after stop: sessions = %+v, %v; want none", sessions, err)
+	}
+
+	press(m, "d")
+	projects, err := store.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 0 || len(m.Projects) != 0 {
+		t.Fatalf("after delete: stored %d, shown %d projects; want none", len(projects), len(m.Projects))
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A session left running by a TUI that died is held for recovery, and
+// discarding the gap logs it up to when it was last seen.
+func TestModelRecoversInterruptedSession(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", 4*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-2 * time.Hour).Round(0)
+	p.Running = true
+	p.StartedAt = start
+	if err := store.Update(p); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StartSession(p.ID, start, ""); err != nil {
+		t.Fatal(err)
+	}
+	seen := start.Add(time.Hour)
+	if err := store.TouchSession(p.ID, "tui:1", seen); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Recoveries) != 1 || m.Projects[0].Running {
+		t.Fatalf("recoveries = %d, running = %v; want the session held", len(m.Recoveries), m.Projects[0].Running)
+	}
+
+	press(m, "x", "enter")
+	logs, err := store.GetLogsByProject(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || !logs[0].StartedAt.Equal(start) || !logs[0].StoppedAt.Equal(seen) {
+		t.Fatalf("logs = %+v, want one from %s to %s", logs, start, seen)
+	}
+	stored, err := store.GetByID(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Running || stored.Elapsed != time.Hour {
+		t.Fatalf("running = %v, elapsed %s; want stopped at 1h0m0s", stored.Running, stored.Elapsed)
+	}
+}
+
+// A session started from the command line is resumed rather than offered
+// for recovery, and closing stops only what the model resumed: a session
+// waiting to be recovered is left for the next launch.
+func TestModelResumesSessionsItDidNotRun(t *testing.T) {
+	store := project.NewMemoryStore()
+	start := time.Now().Add(-2 * time.Hour).Round(0)
+	run := func(name string) *project.Project {
+		t.Helper()
+		p, err := store.Create(name, 4*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Running = true
+		p.StartedAt = start
+		if err := store.Update(p); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.StartSession(p.ID, start, ""); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	cli, crashed := run("Write"), run("Review")
+	if err := store.TouchSession(crashed.ID, "tui:1", start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Recoveries) != 1 || m.Recoveries[0].ProjectID != crashed.ID {
+		t.Fatalf("recoveries = %+v, want only Review's session", m.Recoveries)
+	}
+	if !m.projectByID(cli.ID).Running {
+		t.Fatal("Write's session was not resumed")
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored, err := store.GetByID(cli.ID); err != nil || stored.Running {
+		t.Errorf("Write after close: %+v, %v; want stopped", stored, err)
+	}
+	if logs, err := store.GetLogsByProject(cli.ID); err != nil || len(logs) != 1 {
+		t.Errorf("Write's logs = %+v, %v; want the resumed session", logs, err)
+	}
+	if stored, err := store.GetByID(crashed.ID); err != nil || !stored.Running {
+		t.Errorf("Review after close: %+v, %v; want still running", stored, err)
+	}
+	if sessions, err := store.GetActiveSessions(); err != nil || len(sessions) != 1 || sessions[0].ProjectID != crashed.ID {
+		t.Errorf("sessions = %+v, %v; want Review's left for recovery", sessions, err)
+	}
+}
+
+// A window following a session another window runs leaves it running when
+// it closes, whether the session was running when it opened or started
+// later.
+func TestModelLeavesFollowedSessionsRunning(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	earlier, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	press(owner, "enter")
+	if err := earlier.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	later, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, m := range map[string]*Model{"earlier": earlier, "later": later} {
+		if !m.projectByID(p.ID).Running {
+			t.Errorf("%s window does not show the session", name)
+		}
+		if err := m.Close(); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := store.GetByID(p.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stored.Running {
+			t.Fatalf("closing the %s window stopped the session", name)
+		}
+	}
+	if sessions, err := store.GetActiveSessions(); err != nil || len(sessions) != 1 || sessions[0].Owner != owner.owner {
+		t.Fatalf("sessions = %+v, %v; want the owner's", sessions, err)
+	}
+
+	if err := owner.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := store.GetByID(p.ID); err != nil || stored.Running {
+		t.Fatalf("after the owner closed: %+v, %v; want stopped", stored, err)
+	}
+}
+
+// failingSessions is a store whose sessions cannot be written.
+type failingSessions struct {
+	*project.MemoryStore
+}
+
+var errSessions = errors.New("sessions unavailable")
+
+func (failingSessions) StartSession(int64, time.Time, string) error { return errSessions }
+func (failingSessions) EndSession(int64) error                      { return errSessions }
+
+// Failing to resume a running timer on launch is reported rather than
+// leaving it running without a session to recover.
+func TestModelReportsResumeErrors(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Running = true
+	p.StartedAt = time.Now().Add(-time.Minute)
+	if err := store.Update(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewModel(failingSessions{store}); !errors.Is(err, errSessions) {
+		t.Fatalf("NewModel: %v, want the session error", err)
+	}
+}
+
+// Closing at the tag prompt logs the session up to its stop, not up to the
+// close, with the tag typed so far.
+func TestModelCloseAtTagPrompt(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	press(m, "enter", "enter", "d", "o", "c", "s")
+	stoppedAt := m.PendingLog.StoppedAt
+	time.Sleep(10 * time.Millisecond)
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	logs, err := store.GetLogsByProject(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || !logs[0].StoppedAt.Equal(stoppedAt) || timelog.FormatTags(logs[0].Tags) != "docs" {
+		t.Fatalf("logs = %+v, want one tagged docs stopped at %s", logs, stoppedAt)
+	}
+}
+
+// submit presses enter until the open form is saved, as it moves through
+// the fields first.
+func submit(t *testing.T, m *Model) {
+	t.Helper()
+	for i := 0; m.ShowAddForm || m.ShowEditForm; i++ {
+		if i > 10 || m.Err != nil {
+			t.Fatalf("form not saved: %v", m.Err)
+		}
+		press(m, "enter")
+	}
+}
+
+// A blank duration on the add form makes an open-ended project.
+func TestModelAddOpenEndedProject(t *testing.T) {
+	store := project.NewMemoryStore()
+	m, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	press(m, "n", "S", "u", "p", "p", "o", "r", "t")
+	submit(t, m)
+
+	projects, err := store.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 || projects[0].Name != "Support" || !projects[0].OpenEnded() {
+		t.Fatalf("projects = %+v, want an open-ended Support", projects)
+	}
+}
+
+// Running past the budget notifies through the project's backends.
+func TestModelNotifiesOnCompletion(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Elapsed = time.Minute - 5*time.Millisecond
+	p.Notify = notify.Settings{Backends: []string{notify.Bell}}
+	if err := store.Update(p); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent notify.Recorder
+	m.SetNotifier(&sent)
+
+	press(m, "enter")
+	time.Sleep(10 * time.Millisecond)
+	m.Update(MsgTick{})
+	if len(sent.Sent) != 1 || sent.Sent[0].Kind != notify.Complete || sent.Sent[0].Project != "Write" {
+		t.Fatalf("notified %+v, want Write complete", sent.Sent)
+	}
+	m.Update(MsgTick{})
+	if len(sent.Sent) != 1 {
+		t.Errorf("notified %+v, want complete only once", sent.Sent)
+	}
+}
+
+// Tab at the tag prompt moves to the note, which can span lines.
+func TestModelStopWithNote(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	press(m, "enter", "enter", "d", "o", "c", "s", "tab")
+	press(m, strings.Split("first", "")...)
+	press(m, "ctrl+j")
+	press(m, strings.Split("second", "")...)
+	press(m, "enter")
+	logs, err := store.GetLogsByProject(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || timelog.FormatTags(logs[0].Tags) != "docs" || logs[0].Note != "first\nsecond" {
+		t.Fatalf("logs = %+v, want one tagged docs with a two-line note", logs)
+	}
+}
+
+// Tab at the tag prompt completes the tag being typed from the project's
+// history, cycling back round to what was typed.
+func TestModelCompletesTag(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	log := timelog.TimeLog{ProjectID: p.ID, StartedAt: now.Add(-time.Hour), StoppedAt: now.Add(-time.Minute), Duration: 59 * time.Minute, Tags: []string{"review"}}
+	if err := store.CreateLog(&log); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	press(m, "enter", "enter", "r", "e", "tab")
+	if m.TagInput != "review" || m.NoteFocus {
+		t.Fatalf("after tab: tag %q, note focus %v; want review completed", m.TagInput, m.NoteFocus)
+	}
+	press(m, "tab")
+	if m.TagInput != "re" || !m.NoteFocus {
+		t.Fatalf("after second tab: tag %q, note focus %v; want re with the note focused", m.TagInput, m.NoteFocus)
+	}
+}
+
+// With tags in the project's history but none being typed, Tab moves to the
+// note rather than filling in a suggestion.
+func TestModelTabReachesNote(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	log := timelog.TimeLog{ProjectID: p.ID, StartedAt: now.Add(-time.Hour), StoppedAt: now.Add(-time.Minute), Duration: 59 * time.Minute, Tags: []string{"review"}}
+	if err := store.CreateLog(&log); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	press(m, "enter", "enter")
+	if len(m.TagCompletion.Suggestions) == 0 {
+		t.Fatal("no suggestions shown for an empty tag")
+	}
+	press(m, "tab")
+	if m.TagInput != "" || !m.NoteFocus {
+		t.Fatalf("after tab: tag %q, note focus %v; want the note focused", m.TagInput, m.NoteFocus)
+	}
+	press(m, "h", "i", "enter")
+	logs, err := store.GetLogsByProject(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || logs[0].Note != "hi" || len(logs[0].Tags) != 0 {
+		t.Fatalf("logs = %+v, want the new one noted hi and untagged", logs)
+	}
+}
+
+// Adding, editing and deleting a log in the log viewer queue log events for
+// webhooks, as stopping a timer does.
+func TestModelLogEditsQueueEvents(t *testing.T) {
+	store := project.NewMemoryStore()
+	if _, err := store.Create("Write", 4*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewModel(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetEvents(Events{Webhooks: webhook.New(store, webhook.Config{URLs: []string{"http://example.invalid/hook"}}, nil)})
+	queued := func() []hooks.Payload {
+		t.Helper()
+		msgs, err := store.GetOutbox(time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		payloads := make([]hooks.Payload, len(msgs))
+		for i, msg := range msgs {
+			if err := json.Unmarshal(msg.Payload, &payloads[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return payloads
+	}
+
+	press(m, "l", "a")
+	press(m, strings.Split("2024-05-01 09:00", "")...)
+	press(m, "enter")
+	press(m, strings.Split("2024-05-01 10:00", "")...)
+	press(m, "enter", "c", "a", "l", "l", "enter", "enter", "enter")
+	if m.LogEdit != nil || m.Err != nil {
+		t.Fatalf("log not added: %v", m.Err)
+	}
+	added := queued()
+	if len(added) != 1 || added[0].Event != hooks.Log || added[0].Tag != "call" || added[0].LogID == 0 {
+		t.Fatalf("after adding: queued %+v, want the log", added)
+	}
+
+	press(m, "e", "enter", "enter", "s", "enter", "enter", "enter")
+	edited := queued()
+	if len(edited) != 2 || edited[1].Tag != "calls" || edited[1].LogID != added[0].LogID {
+		t.Fatalf("after editing: queued %+v, want the log again retagged", edited)
+	}
+
+	press(m, "d", "y")
+	deleted := queued()
+	if len(deleted) != 3 || !deleted[2].Deleted || deleted[2].LogID != added[0].LogID {
+		t.Fatalf("after deleting: queued %+v, want the log marked deleted", deleted)
+	}
+}
+
+// Tags, notes and project names are cut to the log viewer's columns by
+// character, so text outside ASCII is neither split nor given less room.
+func TestFormatAllLogsRowCutsByCharacter(t *testing.T) {
+	m := &Model{}
+	lp := project.LogWithProject{
+		ProjectName: strings.Repeat("ü", 20),
+		Log: timelog.TimeLog{
+			StoppedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			Duration:  time.Hour,
+			Tags:      []string{strings.Repeat("é", 10)},
+			Note:      strings.Repeat("ñ", 30),
+		},
+	}
+	row := m.formatAllLogsRow(lp, false)
+	if !utf8.ValidString(row) {
+		t.Fatalf("row %q is not valid UTF-8", row)
+	}
+	for _, want := range []string{strings.Repeat("ü", 13) + "…", strings.Repeat("é", 10) + " ", strings.Repeat("ñ", 16) + "…"} {
+		if !strings.Contains(row, want) {
+			t.Errorf("row %q does not contain %q", row, want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package notify tells the user when a project's budget is nearly or fully
+// used, through the terminal bell, OSC desktop notifications or a command.
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Kind string
+
+const (
+	Warning  Kind = "warning"
+	Complete Kind = "complete"
+)
+
+// Backend names accepted in Settings.
+const (
+	Bell   = "bell"
+	OSC9   = "osc9"
+	OSC777 = "osc777"
+)
+
+// Notification is one event to tell the user about.
+type Notification struct {
+	Project   string
+	Kind      Kind
+	Remaining time.Duration // time left in the budget; zero once complete
+}
+
+func (n Notification) Title() string {
+	return "timer_tui"
+}
+
+func (n Notification) Message() string {
+	if n.Kind == Complete {
+		return fmt.Sprintf("%s: time is up", n.Project)
+	}
+	return fmt.Sprintf("%s: %s left", n.Project, strings.TrimSuffix(n.Remaining.String(), "0s"))
+}
+
+// Settings configures how and when a project notifies. The zero value
+// notifies nothing.
+type Settings struct {
+	Backends []string
+	// Warnings are the times left in the budget at which to warn before it
+	// runs out.
+	Warnings []time.Duration
+	// Command, if set, is run through sh -c for every notification.
+	Command string
+}
+
+// Enabled reports whether the settings deliver notifications anywhere.
+func (s Settings) Enabled() bool {
+	return len(s.Backends) > 0 || s.Command != ""
+}
+
+// Due returns the notifications triggered for a project with the given
+// budget as its elapsed time moves from before to after.
+func (s Settings) Due(project string, maxTime, before, after time.Duration) []Notification {
+	if !s.Enabled() || maxTime <= 0 || after <= before {
+		return nil
+	}
+	var due []Notification
+	for _, w := range s.Warnings {
+		at := maxTime - w
+		if at > 0 && before < at && after >= at {
+			due = append(due, Notification{Project: project, Kind: Warning, Remaining: w})
+		}
+	}
+	if before < maxTime && after >= maxTime {
+		due = append(due, Notification{Project: project, Kind: Complete})
+	}
+	return due
+}
+
+// String formats the backends and warnings the way Parse reads them. The
+// command is kept separately.
+func (s Settings) String() string {
+	out := strings.Join(s.Backends, ",")
+	if len(s.Warnings) > 0 {
+		minutes := make([]string, len(s.Warnings))
+		for i, w := range s.Warnings {
+			minutes[i] = strconv.Itoa(int(w.Minutes()))
+		}
+		out += "@" + strings.Join(minutes, ",")
+	}
+	return out
+}
+
+// Parse reads backends and warnings written as "backends@minutes", e.g.
+// "bell,osc777@5,1" to ring the bell and send a desktop notification 5 and
+// 1 minutes before the budget runs out, and again when it does.
+func Parse(input string) (Settings, error) {
+	var s Settings
+	backends, warnings, _ := strings.Cut(strings.ToLower(strings.TrimSpace(input)), "@")
+
+	for _, b := range strings.Split(backends, ",") {
+		b = strings.TrimSpace(b)
+		switch b {
+		case "":
+		case Bell, OSC9, OSC777:
+			s.Backends = append(s.Backends, b)
+		default:
+			return Settings{}, fmt.Errorf("unknown notification backend %q: want bell, osc9 or osc777", b)
+		}
+	}
+
+	for _, w := range strings.Split(warnings, ",") {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			continue
+		}
+		n, err := strconv.Atoi(w)
+		if err != nil || n <= 0 {
+			return Settings{}, fmt.Errorf("invalid warning %q: want minutes left", w)
+		}
+		s.Warnings = append(s.Warnings, time.Duration(n)*time.Minute)
+	}
+	sort.Slice(s.Warnings, func(i, j int) bool { return s.Warnings[i] > s.Warnings[j] })
+	return s, nil
+}
+
+// Notifier delivers a notification through the backends in s.
+type Notifier interface {
+	Notify(s Settings, n Notification) error
+}
+
+// Terminal writes bell and OSC notifications to Out, normally the terminal
+// the TUI runs in, and starts commands without waiting for them.
+type Terminal struct {
+	Out io.Writer
+}
+
+func (t Terminal) Notify(s Settings, n Notification) error {
+	for _, b := range s.Backends {
+		var seq string
+		switch b {
+		case Bell:
+			seq = "\a"
+		case OSC9:
+			seq = "\x1b]9;" + sanitize(n.Message()) + "\a"
+		case OSC777:
+			seq = "\x1b]777;notify;" + sanitize(n.Title()) + ";" + sanitize(n.Message()) + "\a"
+		}
+		if _, err := io.WriteString(t.Out, seq); err != nil {
+			return err
+		}
+	}
+	if s.Command != "" {
+		return runCommand(s.Command, n)
+	}
+	return nil
+}
+
+// runCommand starts command with the notification in its environment. Its
+// output is discarded so it cannot disturb the TUI.
+func runCommand(command string, n Notification) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"TIMER_TUI_PROJECT="+n.Project,
+		"TIMER_TUI_EVENT="+string(n.Kind),
+		"TIMER_TUI_REMAINING="+strconv.Itoa(int(n.Remaining.Seconds())),
+		"TIMER_TUI_MESSAGE="+n.Message(),
+	)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("notification command: %w", err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// sanitize drops control characters, which would end an OSC sequence early,
+// and semicolons, which separate its fields.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == ';' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Recorder is a Notifier that only records what it was asked to send, for
+// use in place of Terminal in tests.
+type Recorder struct {
+	mu   sync.Mutex
+	Sent []Notification
+}
+
+func (r *Recorder) Notify(s Settings, n Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Sent = append(r.Sent, n)
+	return nil
+}
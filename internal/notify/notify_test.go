@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	s, err := Parse(" bell, OSC777 @ 1,5 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Settings{Backends: []string{Bell, OSC777}, Warnings: []time.Duration{5 * time.Minute, time.Minute}}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("Parse = %+v, want %+v", s, want)
+	}
+	if s.String() != "bell,osc777@5,1" {
+		t.Errorf("String = %q, want bell,osc777@5,1", s.String())
+	}
+
+	for _, input := range []string{"beep", "bell@0", "bell@soon"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestDueOnCrossingEachThreshold(t *testing.T) {
+	s := Settings{Backends: []string{Bell}, Warnings: []time.Duration{5 * time.Minute}}
+	budget := time.Hour
+
+	if due := s.Due("Write", budget, 50*time.Minute, 54*time.Minute); len(due) != 0 {
+		t.Errorf("before the warning: %+v, want none", due)
+	}
+	due := s.Due("Write", budget, 54*time.Minute, 56*time.Minute)
+	if len(due) != 1 || due[0].Kind != Warning || due[0].Remaining != 5*time.Minute {
+		t.Errorf("crossing the warning: %+v, want a 5m warning", due)
+	}
+	// A jump over both thresholds, as after a suspend, sends both.
+	if due := s.Due("Write", budget, 50*time.Minute, 61*time.Minute); len(due) != 2 || due[1].Kind != Complete {
+		t.Errorf("crossing both: %+v, want the warning then complete", due)
+	}
+	if due := s.Due("Write", budget, 61*time.Minute, 70*time.Minute); len(due) != 0 {
+		t.Errorf("past the budget: %+v, want none", due)
+	}
+	if due := (Settings{}).Due("Write", budget, 0, 2*time.Hour); len(due) != 0 {
+		t.Errorf("disabled: %+v, want none", due)
+	}
+}
+
+func TestTerminalWritesSequences(t *testing.T) {
+	var out bytes.Buffer
+	n := Notification{Project: "Write;1", Kind: Complete}
+	if err := (Terminal{Out: &out}).Notify(Settings{Backends: []string{Bell, OSC9, OSC777}}, n); err != nil {
+		t.Fatal(err)
+	}
+	want := "\a\x1b]9;Write1: time is up\a\x1b]777;notify;timer_tui;Write1: time is up\a"
+	if out.String() != want {
+		t.Errorf("wrote %q, want %q", out.String(), want)
+	}
+}
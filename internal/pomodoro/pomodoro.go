@@ -0,0 +1,116 @@
+// Package pomodoro describes work/break cycles for projects run in
+// Pomodoro mode.
+package pomodoro
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Phase string
+
+const (
+	Work       Phase = "work"
+	ShortBreak Phase = "short_break"
+	LongBreak  Phase = "long_break"
+)
+
+func (p Phase) String() string {
+	switch p {
+	case Work:
+		return "Work"
+	case ShortBreak:
+		return "Short break"
+	case LongBreak:
+		return "Long break"
+	}
+	return string(p)
+}
+
+// Settings configures the length of each phase and how many work phases
+// run before a long break.
+type Settings struct {
+	Work       time.Duration
+	ShortBreak time.Duration
+	LongBreak  time.Duration
+	Cycles     int
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		Work:       25 * time.Minute,
+		ShortBreak: 5 * time.Minute,
+		LongBreak:  15 * time.Minute,
+		Cycles:     4,
+	}
+}
+
+// Length returns how long the given phase lasts.
+func (s Settings) Length(p Phase) time.Duration {
+	switch p {
+	case ShortBreak:
+		return s.ShortBreak
+	case LongBreak:
+		return s.LongBreak
+	}
+	return s.Work
+}
+
+// Next returns the phase that follows p, where completed is the number of
+// work phases finished so far including p itself.
+func (s Settings) Next(p Phase, completed int) Phase {
+	if p != Work {
+		return Work
+	}
+	if s.Cycles > 0 && completed%s.Cycles == 0 {
+		return LongBreak
+	}
+	return ShortBreak
+}
+
+// String formats the settings as work/short/long x cycles in minutes, the
+// same form Parse accepts.
+func (s Settings) String() string {
+	return fmt.Sprintf("%d/%d/%dx%d",
+		int(s.Work.Minutes()), int(s.ShortBreak.Minutes()), int(s.LongBreak.Minutes()), s.Cycles)
+}
+
+// Parse reads settings written as "work/short/long x cycles" in minutes,
+// e.g. "25/5/15x4". Missing trailing parts take their default values, and
+// "on" means all defaults.
+func Parse(input string) (Settings, error) {
+	s := DefaultSettings()
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "on" {
+		return s, nil
+	}
+
+	lengths, cycles, hasCycles := strings.Cut(input, "x")
+	if hasCycles {
+		n, err := strconv.Atoi(strings.TrimSpace(cycles))
+		if err != nil || n <= 0 {
+			return s, fmt.Errorf("invalid pomodoro cycle count %q", cycles)
+		}
+		s.Cycles = n
+	}
+
+	fields := []*time.Duration{&s.Work, &s.ShortBreak, &s.LongBreak}
+	parts := strings.Split(lengths, "/")
+	if len(parts) > len(fields) {
+		return s, fmt.Errorf("invalid pomodoro settings %q: want work/short/long x cycles", input)
+	}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n <= 0 {
+			return s, fmt.Errorf("invalid pomodoro length %q", part)
+		}
+		*fields[i] = time.Duration(n) * time.Minute
+	}
+	return s, nil
+}
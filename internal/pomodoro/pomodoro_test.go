@@ -0,0 +1,52 @@
+package pomodoro
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Settings
+	}{
+		{"on", DefaultSettings()},
+		{"25/5/15x4", DefaultSettings()},
+		{"50", Settings{Work: 50 * time.Minute, ShortBreak: 5 * time.Minute, LongBreak: 15 * time.Minute, Cycles: 4}},
+		{"/10 x 2", Settings{Work: 25 * time.Minute, ShortBreak: 10 * time.Minute, LongBreak: 15 * time.Minute, Cycles: 2}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+		if again, err := Parse(got.String()); err != nil || again != got {
+			t.Errorf("Parse(%q) = %v, %v; want it to round-trip", got.String(), again, err)
+		}
+	}
+
+	for _, input := range []string{"0", "25/x/15", "25/5/15/1", "25x0"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestNextTakesALongBreakEveryCycles(t *testing.T) {
+	s := DefaultSettings()
+	var phases []Phase
+	phase, completed := Work, 0
+	for i := 0; i < 8; i++ {
+		if phase == Work {
+			completed++
+		}
+		phase = s.Next(phase, completed)
+		phases = append(phases, phase)
+	}
+	want := []Phase{ShortBreak, Work, ShortBreak, Work, ShortBreak, Work, LongBreak, Work}
+	for i := range want {
+		if phases[i] != want[i] {
+			t.Fatalf("phases = %v, want %v", phases, want)
+		}
+	}
+}
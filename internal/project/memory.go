@@ -0,0 +1,522 @@
+package project
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"timer_tui/internal/timelog"
+)
+
+// MemoryStore is a Store that keeps everything in memory. It needs no
+// database file, which makes it suitable for driving the Model in tests.
+type MemoryStore struct {
+	mu        sync.Mutex
+	projects  map[int64]Project
+	logs      map[int64]timelog.TimeLog
+	sessions  map[int64]timelog.ActiveSession
+	outbox    map[int64]OutboxMessage
+	nextID    int64
+	nextLogID int64
+	nextMsgID int64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		projects: make(map[int64]Project),
+		logs:     make(map[int64]timelog.TimeLog),
+		sessions: make(map[int64]timelog.ActiveSession),
+		outbox:   make(map[int64]OutboxMessage),
+	}
+}
+
+func (s *MemoryStore) GetAll() ([]Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var projects []Project
+	for _, p := range s.projects {
+		projects = append(projects, p)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	return projects, nil
+}
+
+func (s *MemoryStore) GetByID(id int64) (*Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.projects[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &p, nil
+}
+
+func (s *MemoryStore) Create(name string, maxTime time.Duration) (*Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	p := Project{ID: s.nextID, Name: name, MaxTime: maxTime}
+	s.projects[p.ID] = p
+	return &p, nil
+}
+
+func (s *MemoryStore) Update(p *Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.projects[p.ID]
+	if !ok {
+		return nil
+	}
+	if stored.Revision != p.Revision {
+		return ErrConflict
+	}
+	p.Revision++
+	s.projects[p.ID] = *p
+	return nil
+}
+
+func (s *MemoryStore) Delete(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.projects, id)
+	delete(s.sessions, id)
+	for otherID, p := range s.projects {
+		if p.AutoStop != nil && p.AutoStop.FollowUpID == id {
+			autoStop := *p.AutoStop
+			autoStop.FollowUpID = 0
+			p.AutoStop = &autoStop
+			p.Revision++
+			s.projects[otherID] = p
+		}
+	}
+	for logID, l := range s.logs {
+		if l.ProjectID == id {
+			delete(s.logs, logID)
+		}
+	}
+	return nil
+}
+
+// DataVersion is always zero, as nothing outside the process can change a
+// MemoryStore.
+func (s *MemoryStore) DataVersion() (int64, error) {
+	return 0, nil
+}
+
+func (s *MemoryStore) CreateLog(log *timelog.TimeLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[log.ProjectID]; !ok {
+		return fmt.Errorf("project %d does not exist", log.ProjectID)
+	}
+	s.nextLogID++
+	log.ID = s.nextLogID
+	s.logs[log.ID] = *log
+	return nil
+}
+
+func (s *MemoryStore) GetLog(id int64) (*timelog.TimeLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.logs[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &l, nil
+}
+
+func (s *MemoryStore) UpdateLog(log *timelog.TimeLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[log.ProjectID]; !ok {
+		return fmt.Errorf("project %d does not exist", log.ProjectID)
+	}
+	if _, ok := s.logs[log.ID]; ok {
+		s.logs[log.ID] = *log
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteLog(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.logs, id)
+	return nil
+}
+
+func (s *MemoryStore) GetLogsByProject(projectID int64) ([]timelog.TimeLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var logs []timelog.TimeLog
+	for _, l := range s.logs {
+		if l.ProjectID == projectID {
+			logs = append(logs, l)
+		}
+	}
+	sortLogsByStop(logs, func(i int) timelog.TimeLog { return logs[i] })
+	return logs, nil
+}
+
+func (s *MemoryStore) GetAllLogs() ([]LogWithProject, error) {
+	return s.FindLogs(LogFilter{})
+}
+
+func (s *MemoryStore) FindLogs(f LogFilter) ([]LogWithProject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var results []LogWithProject
+	for _, l := range s.logs {
+		if f.ProjectID != 0 && l.ProjectID != f.ProjectID ||
+			!f.From.IsZero() && l.StartedAt.Before(f.From) ||
+			!f.To.IsZero() && !l.StartedAt.Before(f.To) ||
+			!hasTags(l.Tags, f.Tags) ||
+			f.Search != "" && !containsFold(l.Note, f.Search) && !slices.ContainsFunc(l.Tags, func(t string) bool {
+				return containsFold(t, f.Search)
+			}) {
+			continue
+		}
+		results = append(results, LogWithProject{Log: l, ProjectName: s.projects[l.ProjectID].Name})
+	}
+	sortLogsByStop(results, func(i int) timelog.TimeLog { return results[i].Log })
+	return results, nil
+}
+
+func (s *MemoryStore) OverlappingLogs(from, to time.Time, except int64) ([]LogWithProject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var results []LogWithProject
+	for _, l := range s.logs {
+		if l.ID != except && l.StartedAt.Before(to) && l.StoppedAt.After(from) {
+			results = append(results, LogWithProject{Log: l, ProjectName: s.projects[l.ProjectID].Name})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Log.StartedAt.Before(results[j].Log.StartedAt) })
+	return results, nil
+}
+
+func (s *MemoryStore) GetTotals() ([]ProjectTotal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byProject := make(map[int64]*ProjectTotal)
+	var totals []*ProjectTotal
+	for _, p := range s.projects {
+		t := &ProjectTotal{ProjectID: p.ID, ProjectName: p.Name, MaxTime: p.MaxTime}
+		byProject[p.ID] = t
+		totals = append(totals, t)
+	}
+	for _, l := range s.logs {
+		t := byProject[l.ProjectID]
+		t.Logged += l.Duration
+		t.Overtime += l.Overtime
+		t.Sessions++
+		if l.Overtime > 0 {
+			t.Overruns++
+		}
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Overtime != totals[j].Overtime {
+			return totals[i].Overtime > totals[j].Overtime
+		}
+		return totals[i].ProjectName < totals[j].ProjectName
+	})
+
+	results := make([]ProjectTotal, len(totals))
+	for i, t := range totals {
+		results[i] = *t
+	}
+	return results, nil
+}
+
+func (s *MemoryStore) RenameTag(from, to string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := false
+	for id, l := range s.logs {
+		i := slices.IndexFunc(l.Tags, func(t string) bool { return strings.EqualFold(t, from) })
+		if i < 0 {
+			continue
+		}
+		found = true
+		// Logs are shared with clones, so the tags are replaced, not edited.
+		tags := slices.Clone(l.Tags)
+		tags[i] = to
+		l.Tags = timelog.ParseTags(timelog.FormatTags(tags))
+		s.logs[id] = l
+	}
+	if !found {
+		return fmt.Errorf("%w %q", ErrNoTag, from)
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetTagTotals() ([]TagTotal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byTag := make(map[string]*TagTotal)
+	var totals []*TagTotal
+	for _, l := range s.logs {
+		for _, tag := range l.Tags {
+			t, ok := byTag[strings.ToLower(tag)]
+			if !ok {
+				t = &TagTotal{Tag: tag}
+				byTag[strings.ToLower(tag)] = t
+				totals = append(totals, t)
+			}
+			t.Logged += l.Duration
+			t.Overtime += l.Overtime
+			t.Sessions++
+		}
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Logged != totals[j].Logged {
+			return totals[i].Logged > totals[j].Logged
+		}
+		return totals[i].Tag < totals[j].Tag
+	})
+
+	results := make([]TagTotal, len(totals))
+	for i, t := range totals {
+		results[i] = *t
+	}
+	return results, nil
+}
+
+func (s *MemoryStore) SuggestTags(projectID int64, prefix string, now time.Time, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type rank struct {
+		tag              string
+		project, overall float64
+	}
+	byTag := make(map[string]*rank)
+	var ranks []*rank
+	for _, l := range s.logs {
+		weight := 7 / (7 + now.Sub(l.StoppedAt).Hours()/24)
+		for _, tag := range l.Tags {
+			if !strings.HasPrefix(strings.ToLower(tag), strings.ToLower(prefix)) {
+				continue
+			}
+			r, ok := byTag[strings.ToLower(tag)]
+			if !ok {
+				r = &rank{tag: tag}
+				byTag[strings.ToLower(tag)] = r
+				ranks = append(ranks, r)
+			}
+			if l.ProjectID == projectID {
+				r.project += weight
+			}
+			r.overall += weight
+		}
+	}
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].project != ranks[j].project {
+			return ranks[i].project > ranks[j].project
+		}
+		if ranks[i].overall != ranks[j].overall {
+			return ranks[i].overall > ranks[j].overall
+		}
+		return ranks[i].tag < ranks[j].tag
+	})
+
+	var tags []string
+	for _, r := range ranks {
+		if len(tags) == limit {
+			break
+		}
+		tags = append(tags, r.tag)
+	}
+	return tags, nil
+}
+
+// hasTags reports whether tags includes every one of want, ignoring case.
+func hasTags(tags, want []string) bool {
+	for _, w := range want {
+		if !slices.ContainsFunc(tags, func(t string) bool { return strings.EqualFold(t, w) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsFold reports whether substr is in s, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// sortLogsByStop orders logs newest first, matching the SQL queries.
+func sortLogsByStop[T any](logs []T, get func(int) timelog.TimeLog) {
+	sort.SliceStable(logs, func(i, j int) bool {
+		a, b := get(i), get(j)
+		if !a.StoppedAt.Equal(b.StoppedAt) {
+			return a.StoppedAt.After(b.StoppedAt)
+		}
+		return a.ID > b.ID
+	})
+}
+
+func (s *MemoryStore) StartSession(projectID int64, startedAt time.Time, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[projectID]; !ok {
+		return fmt.Errorf("project %d does not exist", projectID)
+	}
+	s.sessions[projectID] = timelog.ActiveSession{
+		ProjectID:  projectID,
+		StartedAt:  startedAt,
+		LastSeenAt: startedAt,
+		Tag:        tag,
+	}
+	return nil
+}
+
+func (s *MemoryStore) TouchSession(projectID int64, owner string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess, ok := s.sessions[projectID]; ok {
+		sess.LastSeenAt = at
+		sess.Owner = owner
+		s.sessions[projectID] = sess
+	}
+	return nil
+}
+
+func (s *MemoryStore) SetSessionTag(projectID int64, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess, ok := s.sessions[projectID]; ok {
+		sess.Tag = tag
+		s.sessions[projectID] = sess
+	}
+	return nil
+}
+
+func (s *MemoryStore) EndSession(projectID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, projectID)
+	return nil
+}
+
+func (s *MemoryStore) GetActiveSessions() ([]timelog.ActiveSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sessions []timelog.ActiveSession
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].StartedAt.Before(sessions[j].StartedAt) })
+	return sessions, nil
+}
+
+func (s *MemoryStore) EnqueueOutbox(msg *OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextMsgID++
+	msg.ID = s.nextMsgID
+	s.outbox[msg.ID] = *msg
+	return nil
+}
+
+func (s *MemoryStore) GetOutbox(due time.Time) ([]OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var messages []OutboxMessage
+	for _, msg := range s.outbox {
+		if due.IsZero() || !msg.NextAttemptAt.After(due) {
+			messages = append(messages, msg)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+func (s *MemoryStore) UpdateOutbox(msg *OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.outbox[msg.ID]; ok {
+		s.outbox[msg.ID] = *msg
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteOutbox(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.outbox, id)
+	return nil
+}
+
+// WithTx snapshots the store and restores the snapshot if fn fails. It does
+// not isolate fn from concurrent callers.
+func (s *MemoryStore) WithTx(fn func(tx Store) error) error {
+	s.mu.Lock()
+	snapshot := s.clone()
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.projects, s.logs, s.sessions, s.outbox = snapshot.projects, snapshot.logs, snapshot.sessions, snapshot.outbox
+		s.nextID, s.nextLogID, s.nextMsgID = snapshot.nextID, snapshot.nextLogID, snapshot.nextMsgID
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *MemoryStore) clone() *MemoryStore {
+	c := &MemoryStore{
+		projects:  make(map[int64]Project, len(s.projects)),
+		logs:      make(map[int64]timelog.TimeLog, len(s.logs)),
+		sessions:  make(map[int64]timelog.ActiveSession, len(s.sessions)),
+		outbox:    make(map[int64]OutboxMessage, len(s.outbox)),
+		nextID:    s.nextID,
+		nextLogID: s.nextLogID,
+		nextMsgID: s.nextMsgID,
+	}
+	for k, v := range s.projects {
+		c.projects[k] = v
+	}
+	for k, v := range s.logs {
+		c.logs[k] = v
+	}
+	for k, v := range s.sessions {
+		c.sessions[k] = v
+	}
+	for k, v := range s.outbox {
+		c.outbox[k] = v
+	}
+	return c
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,396 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is one versioned schema change with the SQL to apply and revert it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied to the database.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrations returns the embedded migrations in version order. Files are named
+// NNNN_name.up.sql and NNNN_name.down.sql.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		name := e.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: missing name", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version", name)
+		}
+
+		body, err := migrationFiles.ReadFile("migrations/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: label}
+			byVersion[version] = mig
+		}
+		if direction == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s: missing up or down file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, mig := range migrations {
+		if mig.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be contiguous from 1, found %d", mig.Version)
+		}
+	}
+	return migrations, nil
+}
+
+// initSchemaVersion creates the schema_version table. A database created
+// before versioning existed is stamped with the versions it already has, so
+// its data is migrated in place instead of being recreated.
+func (r *Repository) initSchemaVersion() error {
+	exists, err := r.hasTable("schema_version")
+	if err != nil || exists {
+		return err
+	}
+
+	legacy, err := r.legacyVersion()
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.db.Exec(`
+	CREATE TABLE schema_version (
+		version INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)
+	`); err != nil {
+		return err
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	for v := 1; v <= legacy; v++ {
+		if _, err := r.db.Exec("INSERT INTO schema_version (version, applied_at) VALUES (?, ?)", v, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyVersion works out how far an unversioned database got by looking for
+// the objects each early migration introduces.
+func (r *Repository) legacyVersion() (int, error) {
+	checks := []func() (bool, error){
+		func() (bool, error) {
+			ok, err := r.hasTable("projects")
+			if !ok || err != nil {
+				return ok, err
+			}
+			return r.hasTable("time_logs")
+		},
+		func() (bool, error) { return r.hasColumn("time_logs", "tag") },
+		func() (bool, error) { return r.hasColumn("projects", "started_at") },
+		func() (bool, error) { return r.hasTable("active_sessions") },
+	}
+
+	version := 0
+	for _, check := range checks {
+		ok, err := check()
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			break
+		}
+		version++
+	}
+	return version, nil
+}
+
+func (r *Repository) hasTable(name string) (bool, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+	return n > 0, err
+}
+
+func (r *Repository) hasColumn(table, column string) (bool, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&n)
+	return n > 0, err
+}
+
+// SchemaVersion returns the highest applied migration version.
+func (r *Repository) SchemaVersion() (int, error) {
+	var v sql.NullInt64
+	if err := r.db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&v); err != nil {
+		return 0, err
+	}
+	return int(v.Int64), nil
+}
+
+// UpToDate reports whether every migration this build has is applied, so the
+// tables have all the columns it reads.
+func (r *Repository) UpToDate() (bool, error) {
+	exists, err := r.hasTable("schema_version")
+	if err != nil || !exists {
+		return false, err
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return false, err
+	}
+	version, err := r.SchemaVersion()
+	if err != nil {
+		return false, err
+	}
+	return version >= len(migrations), nil
+}
+
+func (r *Repository) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version], _ = time.Parse(time.RFC3339, appliedAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, mig := range migrations {
+		at, ok := applied[mig.Version]
+		statuses[i] = MigrationStatus{Migration: mig, Applied: ok, AppliedAt: at}
+	}
+	return statuses, nil
+}
+
+// MigrateUp applies all pending migrations. If the database already holds a
+// schema it is backed up first; the backup path is returned, or "" when no
+// backup was needed.
+func (r *Repository) MigrateUp() (string, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return "", err
+	}
+	current, err := r.SchemaVersion()
+	if err != nil {
+		return "", err
+	}
+	if current > len(migrations) {
+		return "", fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, len(migrations))
+	}
+	if current == len(migrations) {
+		return "", nil
+	}
+
+	backup, err := r.backup(current)
+	if err != nil {
+		return "", err
+	}
+
+	for _, mig := range migrations[current:] {
+		if err := r.applyMigration(mig.Version, mig.Up, true); err != nil {
+			return backup, fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+	return backup, nil
+}
+
+// MigrateDown reverts the most recently applied migration after backing up
+// the database. It returns the backup path.
+func (r *Repository) MigrateDown() (string, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return "", err
+	}
+	current, err := r.SchemaVersion()
+	if err != nil {
+		return "", err
+	}
+	if current == 0 {
+		return "", fmt.Errorf("no migrations to revert")
+	}
+	if current > len(migrations) {
+		return "", fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, len(migrations))
+	}
+
+	backup, err := r.backup(current)
+	if err != nil {
+		return "", err
+	}
+
+	mig := migrations[current-1]
+	if err := r.applyMigration(mig.Version, mig.Down, false); err != nil {
+		return backup, fmt.Errorf("revert %04d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return backup, nil
+}
+
+// applyMigration runs a migration script and records it in schema_version in
+// a single transaction. Foreign keys are off while it runs, as SQLite needs
+// when a table other tables refer to is rebuilt; a violation the script
+// leaves behind fails the migration instead. Violations already there are
+// let through: foreign keys were not enforced before versioning, and the
+// rows they left behind are only cleaned up by a later migration.
+func (r *Repository) applyMigration(version int, script string, up bool) error {
+	ctx := context.Background()
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The pragma is a no-op inside a transaction, so it is set on the
+	// connection first and restored before it goes back to the pool.
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := foreignKeyViolations(tx)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if up {
+		_, err = tx.Exec("INSERT INTO schema_version (version, applied_at) VALUES (?, ?)",
+			version, time.Now().Format(time.RFC3339))
+	} else {
+		_, err = tx.Exec("DELETE FROM schema_version WHERE version = ?", version)
+	}
+	if err != nil {
+		return err
+	}
+
+	after, err := foreignKeyViolations(tx)
+	if err != nil {
+		return err
+	}
+	for v := range after {
+		if !before[v] {
+			return fmt.Errorf("foreign key violation in %s", v.table)
+		}
+	}
+	return tx.Commit()
+}
+
+// fkViolation is a row of pragma_foreign_key_check. Rebuilt tables keep
+// their rowids, so a violation is the same one before and after.
+type fkViolation struct {
+	table  string
+	rowid  int64
+	parent string
+}
+
+func foreignKeyViolations(tx *sql.Tx) (map[fkViolation]bool, error) {
+	rows, err := tx.Query(`SELECT "table", coalesce(rowid, 0), parent FROM pragma_foreign_key_check`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	violations := make(map[fkViolation]bool)
+	for rows.Next() {
+		var v fkViolation
+		if err := rows.Scan(&v.table, &v.rowid, &v.parent); err != nil {
+			return nil, err
+		}
+		violations[v] = true
+	}
+	return violations, rows.Err()
+}
+
+// backup copies the database next to itself before its schema changes. A
+// fresh database with no schema yet has nothing worth keeping.
+func (r *Repository) backup(version int) (string, error) {
+	if version == 0 {
+		return "", nil
+	}
+	base := fmt.Sprintf("%s.v%d-%s", r.path, version, time.Now().Format("20060102-150405"))
+	path := base + ".bak"
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = fmt.Sprintf("%s-%d.bak", base, i)
+	}
+	if _, err := r.db.Exec("VACUUM INTO ?", path); err != nil {
+		return "", fmt.Errorf("backup before migrating: %w", err)
+	}
+	return path, nil
+}
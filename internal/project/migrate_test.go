@@ -0,0 +1,183 @@
+package project
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"timer_tui/internal/timelog"
+)
+
+// baselineSchema is the schema the app created before migrations existed.
+const baselineSchema = `
+CREATE TABLE projects (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	max_time INTEGER NOT NULL,
+	running INTEGER DEFAULT 0,
+	elapsed INTEGER DEFAULT 0
+);
+CREATE TABLE time_logs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	project_id INTEGER NOT NULL,
+	started_at TEXT NOT NULL,
+	stopped_at TEXT NOT NULL,
+	duration INTEGER NOT NULL,
+	tag TEXT NOT NULL DEFAULT '',
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+`
+
+// createBaseline writes a database with the baseline schema and the given
+// statements, and returns its path.
+func createBaseline(t *testing.T, statements string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "timer_tui.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(baselineSchema + statements)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMigrationsAreContiguous(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, mig := range migrations {
+		if mig.Version != i+1 || mig.Up == "" || mig.Down == "" {
+			t.Errorf("migration %d = %04d_%s, want version %d with up and down", i, mig.Version, mig.Name, i+1)
+		}
+	}
+}
+
+// A database from before versioning keeps its data and is brought up to
+// date, after a backup.
+func TestMigrateUpFromBaseline(t *testing.T) {
+	path := createBaseline(t, `
+		INSERT INTO projects (id, name, max_time) VALUES (1, 'Kept', 3600000000000);
+		INSERT INTO time_logs (project_id, started_at, stopped_at, duration, tag) VALUES
+			(1, '2024-05-01T09:00:00Z', '2024-05-01T09:30:00Z', 1800000000000, 'review');
+	`)
+	repo, err := OpenRepository(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	backup, err := repo.MigrateUp()
+	if err != nil {
+		t.Fatalf("migrate baseline database: %v", err)
+	}
+	if _, err := os.Stat(backup); err != nil {
+		t.Errorf("backup %q: %v", backup, err)
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, err := repo.SchemaVersion(); err != nil || version != len(migrations) {
+		t.Fatalf("schema version = %d, %v; want %d", version, err, len(migrations))
+	}
+
+	logs, err := repo.GetAllLogs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || logs[0].ProjectName != "Kept" || timelog.FormatTags(logs[0].Log.Tags) != "review" {
+		t.Fatalf("logs = %+v, want the review log of Kept", logs)
+	}
+}
+
+// A timer left running before starts were stored resumes from the upgrade,
+// so it can be timed and logged.
+func TestMigrateUpResumesRunningBaselineTimer(t *testing.T) {
+	path := createBaseline(t, `
+		INSERT INTO projects (id, name, max_time, running, elapsed) VALUES (1, 'Open', 3600000000000, 1, 600000000000);
+	`)
+	before := time.Now().Truncate(time.Second)
+	repo, err := NewRepository(path)
+	if err != nil {
+		t.Fatalf("open baseline database: %v", err)
+	}
+	defer repo.Close()
+
+	p, err := repo.GetByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Running || p.StartedAt.Before(before) || p.StartedAt.After(time.Now()) {
+		t.Fatalf("running = %v, started at %s, want running since the upgrade at %s", p.Running, p.StartedAt, before)
+	}
+	if p.Elapsed != 10*time.Minute {
+		t.Errorf("elapsed = %s, want 10m0s", p.Elapsed)
+	}
+}
+
+// Foreign keys were not enforced before, so deleting a project left its
+// logs behind. The upgrade removes them.
+func TestMigrateUpDeletesOrphanedLogs(t *testing.T) {
+	path := createBaseline(t, `
+		INSERT INTO projects (id, name, max_time) VALUES (1, 'Kept', 3600000000000);
+		INSERT INTO time_logs (project_id, started_at, stopped_at, duration, tag) VALUES
+			(1, '2024-05-01T09:00:00Z', '2024-05-01T09:30:00Z', 1800000000000, 'review'),
+			(2, '2024-05-01T10:00:00Z', '2024-05-01T10:30:00Z', 1800000000000, 'gone');
+	`)
+	repo, err := NewRepository(path)
+	if err != nil {
+		t.Fatalf("open baseline database: %v", err)
+	}
+	defer repo.Close()
+
+	var logs int
+	if err := repo.db.QueryRow("SELECT COUNT(*) FROM time_logs").Scan(&logs); err != nil {
+		t.Fatal(err)
+	}
+	if logs != 1 {
+		t.Errorf("%d logs left, want only the log of Kept", logs)
+	}
+}
+
+func TestMigrateDownRevertsLatest(t *testing.T) {
+	repo := newTestRepository(t)
+	before, err := repo.SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.MigrateDown(); err != nil {
+		t.Fatal(err)
+	}
+	if after, err := repo.SchemaVersion(); err != nil || after != before-1 {
+		t.Fatalf("schema version after down = %d, %v; want %d", after, err, before-1)
+	}
+	if _, err := repo.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	if after, err := repo.SchemaVersion(); err != nil || after != before {
+		t.Fatalf("schema version after up = %d, %v; want %d", after, err, before)
+	}
+}
+
+// A migration that leaves a violation of its own behind still fails.
+func TestApplyMigrationRejectsNewViolations(t *testing.T) {
+	repo := newTestRepository(t)
+	before, err := repo.SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := `INSERT INTO time_logs (project_id, started_at, stopped_at, duration) VALUES (99, '', '', 0)`
+	if err := repo.applyMigration(before+1, script, true); err == nil {
+		t.Fatal("migration leaving an orphaned log succeeded")
+	}
+	if after, err := repo.SchemaVersion(); err != nil || after != before {
+		t.Errorf("schema version = %d, %v after a failed migration, want %d", after, err, before)
+	}
+}
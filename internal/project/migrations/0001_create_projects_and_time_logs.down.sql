@@ -0,0 +1,2 @@
+DROP TABLE time_logs;
+DROP TABLE projects;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS projects (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	max_time INTEGER NOT NULL,
+	running INTEGER DEFAULT 0,
+	elapsed INTEGER DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS time_logs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	project_id INTEGER NOT NULL,
+	started_at TEXT NOT NULL,
+	stopped_at TEXT NOT NULL,
+	duration INTEGER NOT NULL,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE time_logs ADD COLUMN tag TEXT NOT NULL DEFAULT '';
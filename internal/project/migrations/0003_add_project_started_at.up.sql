@@ -0,0 +1,5 @@
+ALTER TABLE projects ADD COLUMN started_at TEXT;
+
+-- Timers left running before starts were stored resume from the upgrade,
+-- as the app did when it found them, so they can be timed and logged.
+UPDATE projects SET started_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE running = 1;
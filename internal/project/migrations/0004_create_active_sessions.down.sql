@@ -0,0 +1 @@
+DROP TABLE active_sessions;
@@ -0,0 +1,6 @@
+CREATE TABLE active_sessions (
+	project_id INTEGER PRIMARY KEY,
+	started_at TEXT NOT NULL,
+	last_seen_at TEXT NOT NULL,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
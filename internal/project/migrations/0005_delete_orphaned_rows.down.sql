@@ -0,0 +1,2 @@
+-- Deleted orphans cannot be restored; nothing to revert.
+SELECT 1;
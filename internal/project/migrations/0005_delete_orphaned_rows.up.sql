@@ -0,0 +1,4 @@
+-- Foreign keys were never enforced before, so deleting a project left its
+-- time logs and active session behind.
+DELETE FROM time_logs WHERE project_id NOT IN (SELECT id FROM projects);
+DELETE FROM active_sessions WHERE project_id NOT IN (SELECT id FROM projects);
@@ -0,0 +1 @@
+ALTER TABLE active_sessions DROP COLUMN tag;
@@ -0,0 +1 @@
+ALTER TABLE active_sessions ADD COLUMN tag TEXT NOT NULL DEFAULT '';
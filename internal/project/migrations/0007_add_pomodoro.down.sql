@@ -0,0 +1,10 @@
+ALTER TABLE time_logs DROP COLUMN phase;
+
+ALTER TABLE projects DROP COLUMN pomodoro_tag;
+ALTER TABLE projects DROP COLUMN cycle;
+ALTER TABLE projects DROP COLUMN phase_started_at;
+ALTER TABLE projects DROP COLUMN phase;
+ALTER TABLE projects DROP COLUMN pomodoro_cycles;
+ALTER TABLE projects DROP COLUMN pomodoro_long_break;
+ALTER TABLE projects DROP COLUMN pomodoro_short_break;
+ALTER TABLE projects DROP COLUMN pomodoro_work;
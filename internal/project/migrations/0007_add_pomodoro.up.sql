@@ -0,0 +1,10 @@
+ALTER TABLE projects ADD COLUMN pomodoro_work INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE projects ADD COLUMN pomodoro_short_break INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE projects ADD COLUMN pomodoro_long_break INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE projects ADD COLUMN pomodoro_cycles INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE projects ADD COLUMN phase TEXT NOT NULL DEFAULT '';
+ALTER TABLE projects ADD COLUMN phase_started_at TEXT;
+ALTER TABLE projects ADD COLUMN cycle INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE projects ADD COLUMN pomodoro_tag TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE time_logs ADD COLUMN phase TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,28 @@
+-- Open-ended projects get a max time of 0.
+CREATE TABLE projects_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	max_time INTEGER NOT NULL,
+	running INTEGER DEFAULT 0,
+	elapsed INTEGER DEFAULT 0,
+	started_at TEXT,
+	pomodoro_work INTEGER NOT NULL DEFAULT 0,
+	pomodoro_short_break INTEGER NOT NULL DEFAULT 0,
+	pomodoro_long_break INTEGER NOT NULL DEFAULT 0,
+	pomodoro_cycles INTEGER NOT NULL DEFAULT 0,
+	phase TEXT NOT NULL DEFAULT '',
+	phase_started_at TEXT,
+	cycle INTEGER NOT NULL DEFAULT 0,
+	pomodoro_tag TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO projects_new (id, name, max_time, running, elapsed, started_at,
+	pomodoro_work, pomodoro_short_break, pomodoro_long_break, pomodoro_cycles,
+	phase, phase_started_at, cycle, pomodoro_tag)
+SELECT id, name, COALESCE(max_time, 0), running, elapsed, started_at,
+	pomodoro_work, pomodoro_short_break, pomodoro_long_break, pomodoro_cycles,
+	phase, phase_started_at, cycle, pomodoro_tag
+FROM projects;
+
+DROP TABLE projects;
+ALTER TABLE projects_new RENAME TO projects;
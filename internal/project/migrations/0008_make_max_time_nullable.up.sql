@@ -0,0 +1,29 @@
+-- SQLite cannot drop NOT NULL from a column, so the table is rebuilt. A NULL
+-- max_time marks an open-ended project with no budget.
+CREATE TABLE projects_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	max_time INTEGER,
+	running INTEGER DEFAULT 0,
+	elapsed INTEGER DEFAULT 0,
+	started_at TEXT,
+	pomodoro_work INTEGER NOT NULL DEFAULT 0,
+	pomodoro_short_break INTEGER NOT NULL DEFAULT 0,
+	pomodoro_long_break INTEGER NOT NULL DEFAULT 0,
+	pomodoro_cycles INTEGER NOT NULL DEFAULT 0,
+	phase TEXT NOT NULL DEFAULT '',
+	phase_started_at TEXT,
+	cycle INTEGER NOT NULL DEFAULT 0,
+	pomodoro_tag TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO projects_new (id, name, max_time, running, elapsed, started_at,
+	pomodoro_work, pomodoro_short_break, pomodoro_long_break, pomodoro_cycles,
+	phase, phase_started_at, cycle, pomodoro_tag)
+SELECT id, name, max_time, running, elapsed, started_at,
+	pomodoro_work, pomodoro_short_break, pomodoro_long_break, pomodoro_cycles,
+	phase, phase_started_at, cycle, pomodoro_tag
+FROM projects;
+
+DROP TABLE projects;
+ALTER TABLE projects_new RENAME TO projects;
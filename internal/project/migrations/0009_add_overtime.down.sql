@@ -0,0 +1,2 @@
+ALTER TABLE time_logs DROP COLUMN overtime;
+ALTER TABLE projects DROP COLUMN overtime;
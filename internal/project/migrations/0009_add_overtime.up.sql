@@ -0,0 +1,7 @@
+ALTER TABLE projects ADD COLUMN overtime INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE time_logs ADD COLUMN overtime INTEGER NOT NULL DEFAULT 0;
+
+-- Time already past the budget counts as overtime on the project. Existing
+-- logs cannot be split after the fact and stay in budget.
+UPDATE projects SET overtime = elapsed - max_time
+WHERE max_time IS NOT NULL AND elapsed > max_time;
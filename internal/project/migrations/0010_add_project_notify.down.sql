@@ -0,0 +1,2 @@
+ALTER TABLE projects DROP COLUMN notify_command;
+ALTER TABLE projects DROP COLUMN notify;
@@ -0,0 +1,2 @@
+ALTER TABLE projects ADD COLUMN notify TEXT NOT NULL DEFAULT '';
+ALTER TABLE projects ADD COLUMN notify_command TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE projects ADD COLUMN revision INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE projects DROP COLUMN follow_up_id;
+ALTER TABLE projects DROP COLUMN auto_stop_tag;
+ALTER TABLE projects DROP COLUMN auto_stop;
@@ -0,0 +1,3 @@
+ALTER TABLE projects ADD COLUMN auto_stop INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE projects ADD COLUMN auto_stop_tag TEXT NOT NULL DEFAULT '';
+ALTER TABLE projects ADD COLUMN follow_up_id INTEGER;
@@ -0,0 +1 @@
+ALTER TABLE time_logs DROP COLUMN note;
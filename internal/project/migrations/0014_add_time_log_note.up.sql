@@ -0,0 +1 @@
+ALTER TABLE time_logs ADD COLUMN note TEXT NOT NULL DEFAULT '';
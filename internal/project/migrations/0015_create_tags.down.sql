@@ -0,0 +1,9 @@
+ALTER TABLE time_logs ADD COLUMN tag TEXT NOT NULL DEFAULT '';
+UPDATE time_logs SET tag = coalesce((
+	SELECT group_concat(t.name, ', ' ORDER BY lt.position)
+	FROM time_log_tags lt JOIN tags t ON t.id = lt.tag_id
+	WHERE lt.time_log_id = time_logs.id
+), '');
+
+DROP TABLE time_log_tags;
+DROP TABLE tags;
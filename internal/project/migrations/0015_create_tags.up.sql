@@ -0,0 +1,32 @@
+CREATE TABLE tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE COLLATE NOCASE
+);
+
+CREATE TABLE time_log_tags (
+	time_log_id INTEGER NOT NULL REFERENCES time_logs(id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (time_log_id, tag_id)
+);
+
+CREATE INDEX time_log_tags_tag_id ON time_log_tags (tag_id);
+
+-- Existing tags are split the way the tag prompt reads them now: on "#" and
+-- on commas.
+CREATE TEMP TABLE split_tags AS
+WITH RECURSIVE split(log_id, rest, name, position) AS (
+	SELECT id, replace(tag, '#', ',') || ',', '', -1 FROM time_logs WHERE trim(tag) != ''
+	UNION ALL
+	SELECT log_id, substr(rest, instr(rest, ',') + 1), trim(substr(rest, 1, instr(rest, ',') - 1)), position + 1
+	FROM split WHERE rest != ''
+)
+SELECT log_id, name, position FROM split WHERE name != '';
+
+INSERT OR IGNORE INTO tags (name) SELECT name FROM split_tags ORDER BY log_id, position;
+INSERT OR IGNORE INTO time_log_tags (time_log_id, tag_id, position)
+	SELECT s.log_id, t.id, s.position FROM split_tags s JOIN tags t ON t.name = s.name
+	ORDER BY s.log_id, s.position;
+DROP TABLE split_tags;
+
+ALTER TABLE time_logs DROP COLUMN tag;
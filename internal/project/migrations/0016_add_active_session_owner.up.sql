@@ -0,0 +1,3 @@
+-- The TUI running a session, which keeps it alive. Sessions started from the
+-- command line or the HTTP API have none.
+ALTER TABLE active_sessions ADD COLUMN owner TEXT NOT NULL DEFAULT '';
@@ -1,15 +1,62 @@
 package project
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"timer_tui/internal/notify"
+	"timer_tui/internal/pomodoro"
+)
 
 type Project struct {
-	ID      int64
-	Name    string
+	ID   int64
+	Name string
+	// MaxTime is the project's time budget. Zero makes it an open-ended
+	// stopwatch that counts up with no budget.
 	MaxTime time.Duration
 	Running bool
 	Elapsed time.Duration
+
+	// StartedAt is the wall-clock start of the running session. While a
+	// project is running, the stored Elapsed is the time banked before it.
+	StartedAt time.Time
+
+	// Pomodoro holds the work/break settings, or nil for a plain countdown.
+	Pomodoro *pomodoro.Settings
+	// Phase, PhaseStartedAt and Cycle track a running pomodoro: the current
+	// phase, when it began and how many work phases have been completed.
+	// During a break the project stays Running but StartedAt is zero, as
+	// break time is not counted.
+	Phase          pomodoro.Phase
+	PhaseStartedAt time.Time
+	Cycle          int
+	// PomodoroTag is the tag the pomodoro was started with, kept here as
+	// breaks have no active session to hold it.
+	PomodoroTag string
+
+	// Notify configures the warnings and completion notice for the budget.
+	Notify notify.Settings
+	// AutoStop stops the timer once the budget is used up, or is nil to let
+	// it run on into overtime.
+	AutoStop *AutoStop
+
+	// Revision counts the saves of the project. An update is only applied
+	// if the project was not saved by anyone else since it was loaded.
+	Revision int64
+}
+
+// AutoStop is what happens when a running project uses up its budget.
+type AutoStop struct {
+	// Tag is the tag the stopped session is logged with.
+	Tag string
+	// FollowUpID is the project started next, or zero for none.
+	FollowUpID int64
 }
 
+// ErrConflict is returned when saving a project that another instance has
+// saved since it was loaded.
+var ErrConflict = errors.New("project was changed by another instance")
+
 func NewProject(name string, maxTime time.Duration) *Project {
 	return &Project{
 		Name:    name,
@@ -19,13 +66,67 @@ func NewProject(name string, maxTime time.Duration) *Project {
 	}
 }
 
+// OpenEnded reports whether the project has no time budget.
+func (p *Project) OpenEnded() bool {
+	return p.MaxTime <= 0
+}
+
+// Remaining returns the time left in the budget, which is always zero for an
+// open-ended project.
 func (p *Project) Remaining() time.Duration {
-	if p.Elapsed >= p.MaxTime {
+	if p.OpenEnded() || p.Elapsed >= p.MaxTime {
 		return 0
 	}
 	return p.MaxTime - p.Elapsed
 }
 
+// IsComplete reports whether the budget has been used up. An open-ended
+// project is never complete.
 func (p *Project) IsComplete() bool {
-	return p.Elapsed >= p.MaxTime
+	return !p.OpenEnded() && p.Elapsed >= p.MaxTime
+}
+
+// Overtime returns how far Elapsed has run past the budget.
+func (p *Project) Overtime() time.Duration {
+	return p.OverBudget(0, p.Elapsed)
+}
+
+// OverBudget returns how much of the time between two elapsed totals lies
+// past the budget. Open-ended projects have no overtime.
+func (p *Project) OverBudget(from, to time.Duration) time.Duration {
+	if p.OpenEnded() {
+		return 0
+	}
+	return max(to-max(from, p.MaxTime), 0)
+}
+
+// PhaseEnd returns when the current pomodoro phase ends, or the zero time if
+// the project is not running a pomodoro.
+func (p *Project) PhaseEnd() time.Time {
+	if p.Pomodoro == nil || !p.Running || p.PhaseStartedAt.IsZero() {
+		return time.Time{}
+	}
+	return p.PhaseStartedAt.Add(p.Pomodoro.Length(p.Phase))
+}
+
+// PomodoroCycle returns which work phase of the current set (1 to
+// Pomodoro.Cycles) is running, or has just finished during a break.
+func (p *Project) PomodoroCycle() int {
+	if p.Pomodoro == nil || p.Pomodoro.Cycles <= 0 {
+		return 0
+	}
+	done := p.Cycle
+	if p.Phase != pomodoro.Work && done > 0 {
+		done--
+	}
+	return done%p.Pomodoro.Cycles + 1
+}
+
+// ElapsedAt returns the total elapsed time at now for a project as stored,
+// where Elapsed excludes the running session.
+func (p *Project) ElapsedAt(now time.Time) time.Duration {
+	if !p.Running || p.StartedAt.IsZero() || now.Before(p.StartedAt) {
+		return p.Elapsed
+	}
+	return p.Elapsed + now.Sub(p.StartedAt)
 }
@@ -0,0 +1,27 @@
+package project
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOverBudget(t *testing.T) {
+	budgeted := &Project{MaxTime: time.Hour}
+	tests := []struct {
+		from, to, want time.Duration
+	}{
+		{0, 50 * time.Minute, 0},
+		{50 * time.Minute, 70 * time.Minute, 10 * time.Minute},
+		{65 * time.Minute, 70 * time.Minute, 5 * time.Minute},
+		{0, time.Hour, 0},
+	}
+	for _, tt := range tests {
+		if got := budgeted.OverBudget(tt.from, tt.to); got != tt.want {
+			t.Errorf("OverBudget(%s, %s) = %s, want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	if got := (&Project{}).OverBudget(0, 10*time.Hour); got != 0 {
+		t.Errorf("open-ended OverBudget = %s, want 0", got)
+	}
+}
@@ -1,68 +1,192 @@
 package project
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"timer_tui/internal/notify"
+	"timer_tui/internal/pomodoro"
 	"timer_tui/internal/timelog"
 
 	_ "modernc.org/sqlite"
 )
 
 type Repository struct {
-	db *sql.DB
+	db   *sql.DB
+	tx   *sql.Tx // set on the copy passed to a WithTx callback
+	path string
+	// watch is the connection DataVersion asks, which must be the same one
+	// every time for the answer to mean anything.
+	watch *sql.Conn
 }
 
-func NewRepository() (*Repository, error) {
-	db, err := sql.Open("sqlite", "timer_tui.db")
+// dbtx is the part of *sql.DB and *sql.Tx the repository queries through,
+// so the same methods work inside and outside a transaction.
+type dbtx interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// NewRepository opens the database at path and applies any pending migrations.
+func NewRepository(path string) (*Repository, error) {
+	repo, err := OpenRepository(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := repo.MigrateUp(); err != nil {
+		repo.Close()
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// OpenRepository opens the database at path without migrating its schema,
+// creating its directory if needed.
+func OpenRepository(path string) (*Repository, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	// Foreign keys are off by default in SQLite and are set per connection,
+	// so they are enabled in the DSN along with WAL journaling. The busy
+	// timeout lets writers, here or in another instance, wait for a
+	// transaction instead of failing with "database is locked". Transactions
+	// take the write lock up front: one that read first and then tried to
+	// write after another connection committed would fail outright.
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate")
 	if err != nil {
 		return nil, err
 	}
 
 	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	watch, err := db.Conn(context.Background())
+	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	repo := &Repository{db: db}
-	if err := repo.init(); err != nil {
+	repo := &Repository{db: db, path: path, watch: watch}
+	if err := repo.initSchemaVersion(); err != nil {
+		repo.Close()
 		return nil, err
 	}
 
 	return repo, nil
 }
 
-func (r *Repository) init() error {
-	projectsQuery := `
-	CREATE TABLE IF NOT EXISTS projects (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		max_time INTEGER NOT NULL,
-		running INTEGER DEFAULT 0,
-		elapsed INTEGER DEFAULT 0
-	)
-	`
-	if _, err := r.db.Exec(projectsQuery); err != nil {
+// OpenReadOnly opens an existing database for reading only. Nothing is
+// written to its files, not even a WAL checkpoint on close, so a reader can
+// tell from their modification times whether anyone else has written.
+func OpenReadOnly(path string) (*Repository, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	watch, err := db.Conn(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Repository{db: db, path: path, watch: watch}, nil
+}
+
+func (r *Repository) conn() dbtx {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.db
+}
+
+// WithTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise. The repository passed to fn issues all its queries in the
+// transaction; calling WithTx on it again simply joins the outer one.
+func (r *Repository) WithTx(fn func(tx Store) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
 		return err
 	}
+	if err := fn(&Repository{db: r.db, tx: tx, path: r.path, watch: r.watch}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
 
-	timeLogsQuery := `
-	CREATE TABLE IF NOT EXISTS time_logs (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		project_id INTEGER NOT NULL,
-		started_at TEXT NOT NULL,
-		stopped_at TEXT NOT NULL,
-		duration INTEGER NOT NULL,
-		tag TEXT NOT NULL DEFAULT '',
-		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
-	)
-	`
-	_, err := r.db.Exec(timeLogsQuery)
-	return err
+const projectColumns = `id, name, max_time, running, elapsed, started_at,
+	pomodoro_work, pomodoro_short_break, pomodoro_long_break, pomodoro_cycles,
+	phase, phase_started_at, cycle, pomodoro_tag, notify, notify_command, revision,
+	auto_stop, auto_stop_tag, follow_up_id`
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProject(row scanner) (*Project, error) {
+	var p Project
+	var maxTime sql.NullInt64
+	var elapsed int64
+	var running int
+	var startedAt, phaseStartedAt sql.NullString
+	var pomo pomodoro.Settings
+	var phase, notifySettings string
+	var autoStop AutoStop
+	var autoStopOn int
+	var followUp sql.NullInt64
+	if err := row.Scan(
+		&p.ID, &p.Name, &maxTime, &running, &elapsed, &startedAt,
+		&pomo.Work, &pomo.ShortBreak, &pomo.LongBreak, &pomo.Cycles,
+		&phase, &phaseStartedAt, &p.Cycle, &p.PomodoroTag, &notifySettings, &p.Notify.Command,
+		&p.Revision, &autoStopOn, &autoStop.Tag, &followUp,
+	); err != nil {
+		return nil, err
+	}
+	p.MaxTime = time.Duration(maxTime.Int64)
+	p.Running = running == 1
+	p.Elapsed = time.Duration(elapsed)
+	p.StartedAt = parseNullTime(startedAt)
+	if pomo.Work > 0 {
+		p.Pomodoro = &pomo
+	}
+	p.Phase = pomodoro.Phase(phase)
+	p.PhaseStartedAt = parseNullTime(phaseStartedAt)
+	// Settings are validated when entered, so a bad value is just dropped.
+	if settings, err := notify.Parse(notifySettings); err == nil {
+		settings.Command = p.Notify.Command
+		p.Notify = settings
+	}
+	if autoStopOn == 1 {
+		autoStop.FollowUpID = followUp.Int64
+		p.AutoStop = &autoStop
+	}
+	return &p, nil
 }
 
 func (r *Repository) GetAll() ([]Project, error) {
-	rows, err := r.db.Query("SELECT id, name, max_time, running, elapsed FROM projects")
+	rows, err := r.conn().Query("SELECT " + projectColumns + " FROM projects")
 	if err != nil {
 		return nil, err
 	}
@@ -70,39 +194,23 @@ func (r *Repository) GetAll() ([]Project, error) {
 
 	var projects []Project
 	for rows.Next() {
-		var p Project
-		var maxTime, elapsed int64
-		var running int
-		if err := rows.Scan(&p.ID, &p.Name, &maxTime, &running, &elapsed); err != nil {
+		p, err := scanProject(rows)
+		if err != nil {
 			return nil, err
 		}
-		p.MaxTime = time.Duration(maxTime)
-		p.Running = running == 1
-		p.Elapsed = time.Duration(elapsed)
-		projects = append(projects, p)
+		projects = append(projects, *p)
 	}
 	return projects, nil
 }
 
 func (r *Repository) GetByID(id int64) (*Project, error) {
-	var p Project
-	var maxTime, elapsed int64
-	var running int
-	err := r.db.QueryRow("SELECT id, name, max_time, running, elapsed FROM projects WHERE id = ?", id).
-		Scan(&p.ID, &p.Name, &maxTime, &running, &elapsed)
-	if err != nil {
-		return nil, err
-	}
-	p.MaxTime = time.Duration(maxTime)
-	p.Running = running == 1
-	p.Elapsed = time.Duration(elapsed)
-	return &p, nil
+	return scanProject(r.conn().QueryRow("SELECT "+projectColumns+" FROM projects WHERE id = ?", id))
 }
 
 func (r *Repository) Create(name string, maxTime time.Duration) (*Project, error) {
-	result, err := r.db.Exec(
+	result, err := r.conn().Exec(
 		"INSERT INTO projects (name, max_time, running, elapsed) VALUES (?, ?, 0, 0)",
-		name, int64(maxTime),
+		name, nullDuration(maxTime),
 	)
 	if err != nil {
 		return nil, err
@@ -122,36 +230,94 @@ func (r *Repository) Create(name string, maxTime time.Duration) (*Project, error
 	}, nil
 }
 
+// Update saves p and moves its revision on. It fails with ErrConflict if the
+// stored project has been saved since p was loaded. Its overtime is stored
+// alongside so reports can query it.
 func (r *Repository) Update(p *Project) error {
 	running := 0
 	if p.Running {
 		running = 1
 	}
-	_, err := r.db.Exec(
-		"UPDATE projects SET name = ?, max_time = ?, running = ?, elapsed = ? WHERE id = ?",
-		p.Name, int64(p.MaxTime), running, int64(p.Elapsed), p.ID,
+	var pomo pomodoro.Settings
+	if p.Pomodoro != nil {
+		pomo = *p.Pomodoro
+	}
+	autoStopOn := 0
+	var autoStop AutoStop
+	if p.AutoStop != nil {
+		autoStopOn = 1
+		autoStop = *p.AutoStop
+	}
+	followUp := sql.NullInt64{Int64: autoStop.FollowUpID, Valid: autoStop.FollowUpID != 0}
+	result, err := r.conn().Exec(
+		`UPDATE projects SET name = ?, max_time = ?, running = ?, elapsed = ?, started_at = ?,
+			pomodoro_work = ?, pomodoro_short_break = ?, pomodoro_long_break = ?, pomodoro_cycles = ?,
+			phase = ?, phase_started_at = ?, cycle = ?, pomodoro_tag = ?, overtime = ?,
+			notify = ?, notify_command = ?, auto_stop = ?, auto_stop_tag = ?, follow_up_id = ?,
+			revision = revision + 1
+		 WHERE id = ? AND revision = ?`,
+		p.Name, nullDuration(p.MaxTime), running, int64(p.Elapsed), formatNullTime(p.StartedAt),
+		int64(pomo.Work), int64(pomo.ShortBreak), int64(pomo.LongBreak), pomo.Cycles,
+		string(p.Phase), formatNullTime(p.PhaseStartedAt), p.Cycle, p.PomodoroTag, int64(p.Overtime()),
+		p.Notify.String(), p.Notify.Command, autoStopOn, autoStop.Tag, followUp,
+		p.ID, p.Revision,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 1 {
+		p.Revision++
+		return nil
+	}
+
+	// Nothing matched: either the project is gone, which is not an error
+	// here, or its revision moved on.
+	var exists int
+	err = r.conn().QueryRow("SELECT 1 FROM projects WHERE id = ?", p.ID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrConflict
 }
 
+// Delete removes the project and its logs. Projects that were set to start
+// it after auto-stopping no longer start anything.
 func (r *Repository) Delete(id int64) error {
-	_, err := r.db.Exec("DELETE FROM projects WHERE id = ?", id)
+	if _, err := r.conn().Exec(
+		"UPDATE projects SET follow_up_id = NULL, revision = revision + 1 WHERE follow_up_id = ?", id,
+	); err != nil {
+		return err
+	}
+	_, err := r.conn().Exec("DELETE FROM projects WHERE id = ?", id)
 	return err
 }
 
-func (r *Repository) StopAllTimers() error {
-	_, err := r.db.Exec("UPDATE projects SET running = 0")
-	return err
+// DataVersion returns a number that changes whenever a change is committed
+// through any other connection to the database, including those of other
+// instances.
+func (r *Repository) DataVersion() (int64, error) {
+	var v int64
+	err := r.watch.QueryRowContext(context.Background(), "PRAGMA data_version").Scan(&v)
+	return v, err
 }
 
 func (r *Repository) CreateLog(log *timelog.TimeLog) error {
-	result, err := r.db.Exec(
-		"INSERT INTO time_logs (project_id, started_at, stopped_at, duration, tag) VALUES (?, ?, ?, ?, ?)",
+	result, err := r.conn().Exec(
+		"INSERT INTO time_logs (project_id, started_at, stopped_at, duration, phase, overtime, note) VALUES (?, ?, ?, ?, ?, ?, ?)",
 		log.ProjectID,
 		log.StartedAt.Format(time.RFC3339),
 		log.StoppedAt.Format(time.RFC3339),
 		int64(log.Duration),
-		log.Tag,
+		log.Phase,
+		int64(log.Overtime),
+		log.Note,
 	)
 	if err != nil {
 		return err
@@ -161,12 +327,94 @@ func (r *Repository) CreateLog(log *timelog.TimeLog) error {
 		return err
 	}
 	log.ID = id
+	return r.setLogTags(log.ID, log.Tags)
+}
+
+// setLogTags links a log to its tags in order, creating the tags not used
+// before. Tag names match ignoring case.
+func (r *Repository) setLogTags(logID int64, tags []string) error {
+	if _, err := r.conn().Exec("DELETE FROM time_log_tags WHERE time_log_id = ?", logID); err != nil {
+		return err
+	}
+	for i, tag := range tags {
+		if _, err := r.conn().Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+			return err
+		}
+		if _, err := r.conn().Exec(
+			"INSERT OR IGNORE INTO time_log_tags (time_log_id, tag_id, position) SELECT ?, id, ? FROM tags WHERE name = ?",
+			logID, i, tag,
+		); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// tagSeparator joins a log's tag names in logColumns. Tags cannot be typed
+// with it.
+const tagSeparator = "\x1f"
+
+const logColumns = `tl.id, tl.project_id, tl.started_at, tl.stopped_at, tl.duration,
+	COALESCE((SELECT group_concat(t.name, char(31) ORDER BY lt.position)
+		FROM time_log_tags lt JOIN tags t ON t.id = lt.tag_id
+		WHERE lt.time_log_id = tl.id), ''),
+	tl.phase, tl.overtime, tl.note`
+
+// scanLog scans logColumns followed by any extra destinations.
+func scanLog(row scanner, extra ...any) (timelog.TimeLog, error) {
+	var l timelog.TimeLog
+	var startedAt, stoppedAt, tags string
+	var duration, overtime int64
+	dest := append([]any{&l.ID, &l.ProjectID, &startedAt, &stoppedAt, &duration, &tags, &l.Phase, &overtime, &l.Note}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return l, err
+	}
+	if tags != "" {
+		l.Tags = strings.Split(tags, tagSeparator)
+	}
+	l.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
+	l.StoppedAt, _ = time.Parse(time.RFC3339, stoppedAt)
+	l.Duration = time.Duration(duration)
+	l.Overtime = time.Duration(overtime)
+	return l, nil
+}
+
+func (r *Repository) GetLog(id int64) (*timelog.TimeLog, error) {
+	l, err := scanLog(r.conn().QueryRow("SELECT "+logColumns+" FROM time_logs tl WHERE tl.id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *Repository) UpdateLog(log *timelog.TimeLog) error {
+	_, err := r.conn().Exec(
+		`UPDATE time_logs SET project_id = ?, started_at = ?, stopped_at = ?, duration = ?,
+			phase = ?, overtime = ?, note = ?
+		 WHERE id = ?`,
+		log.ProjectID,
+		log.StartedAt.Format(time.RFC3339),
+		log.StoppedAt.Format(time.RFC3339),
+		int64(log.Duration),
+		log.Phase,
+		int64(log.Overtime),
+		log.Note,
+		log.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return r.setLogTags(log.ID, log.Tags)
+}
+
+func (r *Repository) DeleteLog(id int64) error {
+	_, err := r.conn().Exec("DELETE FROM time_logs WHERE id = ?", id)
+	return err
+}
+
 func (r *Repository) GetLogsByProject(projectID int64) ([]timelog.TimeLog, error) {
-	rows, err := r.db.Query(
-		"SELECT id, project_id, started_at, stopped_at, duration, tag FROM time_logs WHERE project_id = ? ORDER BY stopped_at DESC",
+	rows, err := r.conn().Query(
+		"SELECT "+logColumns+" FROM time_logs tl WHERE tl.project_id = ? ORDER BY tl.stopped_at DESC",
 		projectID,
 	)
 	if err != nil {
@@ -176,15 +424,10 @@ func (r *Repository) GetLogsByProject(projectID int64) ([]timelog.TimeLog, error
 
 	var logs []timelog.TimeLog
 	for rows.Next() {
-		var l timelog.TimeLog
-		var startedAt, stoppedAt string
-		var duration int64
-		if err := rows.Scan(&l.ID, &l.ProjectID, &startedAt, &stoppedAt, &duration, &l.Tag); err != nil {
+		l, err := scanLog(rows)
+		if err != nil {
 			return nil, err
 		}
-		l.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
-		l.StoppedAt, _ = time.Parse(time.RFC3339, stoppedAt)
-		l.Duration = time.Duration(duration)
 		logs = append(logs, l)
 	}
 	return logs, nil
@@ -197,11 +440,86 @@ type LogWithProject struct {
 }
 
 func (r *Repository) GetAllLogs() ([]LogWithProject, error) {
-	rows, err := r.db.Query(
-		`SELECT tl.id, tl.project_id, p.name, tl.started_at, tl.stopped_at, tl.duration, tl.tag
+	return r.FindLogs(LogFilter{})
+}
+
+// LogFilter selects time logs. Zero fields match everything.
+type LogFilter struct {
+	ProjectID int64
+	// From and To bound when the logs started: From <= start < To.
+	From, To time.Time
+	// Tags matches logs that have every one of them.
+	Tags []string
+	// Search matches logs with a tag or note that contains it, ignoring
+	// case.
+	Search string
+}
+
+// FindLogs returns the logs matching f, newest first.
+func (r *Repository) FindLogs(f LogFilter) ([]LogWithProject, error) {
+	var where []string
+	var args []any
+	if f.ProjectID != 0 {
+		where = append(where, "tl.project_id = ?")
+		args = append(args, f.ProjectID)
+	}
+	// Start times carry their UTC offset, so they are compared through
+	// julianday rather than as text.
+	if !f.From.IsZero() {
+		where = append(where, "julianday(tl.started_at) >= julianday(?)")
+		args = append(args, f.From.UTC().Format(time.RFC3339))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "julianday(tl.started_at) < julianday(?)")
+		args = append(args, f.To.UTC().Format(time.RFC3339))
+	}
+	for _, tag := range f.Tags {
+		where = append(where, `EXISTS (
+			SELECT 1 FROM time_log_tags lt JOIN tags t ON t.id = lt.tag_id
+			WHERE lt.time_log_id = tl.id AND t.name = ?)`)
+		args = append(args, tag)
+	}
+	if f.Search != "" {
+		pattern := "%" + escapeLike(f.Search) + "%"
+		where = append(where, `(tl.note LIKE ? ESCAPE '\' OR EXISTS (
+			SELECT 1 FROM time_log_tags lt JOIN tags t ON t.id = lt.tag_id
+			WHERE lt.time_log_id = tl.id AND t.name LIKE ? ESCAPE '\'))`)
+		args = append(args, pattern, pattern)
+	}
+
+	query := `SELECT ` + logColumns + `, p.name
+		 FROM time_logs tl
+		 JOIN projects p ON tl.project_id = p.id`
+	if len(where) > 0 {
+		query += "\n\t\t WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := r.conn().Query(query+"\n\t\t ORDER BY tl.stopped_at DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []LogWithProject
+	for rows.Next() {
+		var lp LogWithProject
+		lp.Log, err = scanLog(rows, &lp.ProjectName)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, lp)
+	}
+	return results, nil
+}
+
+func (r *Repository) OverlappingLogs(from, to time.Time, except int64) ([]LogWithProject, error) {
+	rows, err := r.conn().Query(
+		`SELECT `+logColumns+`, p.name
 		 FROM time_logs tl
 		 JOIN projects p ON tl.project_id = p.id
-		 ORDER BY tl.stopped_at DESC`,
+		 WHERE julianday(tl.started_at) < julianday(?) AND julianday(tl.stopped_at) > julianday(?)
+		   AND tl.id != ?
+		 ORDER BY tl.started_at`,
+		to.UTC().Format(time.RFC3339), from.UTC().Format(time.RFC3339), except,
 	)
 	if err != nil {
 		return nil, err
@@ -211,26 +529,338 @@ func (r *Repository) GetAllLogs() ([]LogWithProject, error) {
 	var results []LogWithProject
 	for rows.Next() {
 		var lp LogWithProject
-		var startedAt, stoppedAt string
-		var duration int64
-		if err := rows.Scan(
-			&lp.Log.ID, &lp.Log.ProjectID, &lp.ProjectName,
-			&startedAt, &stoppedAt, &duration, &lp.Log.Tag,
-		); err != nil {
+		lp.Log, err = scanLog(rows, &lp.ProjectName)
+		if err != nil {
 			return nil, err
 		}
-		lp.Log.StartedAt, _ = time.Parse(time.RFC3339, startedAt)
-		lp.Log.StoppedAt, _ = time.Parse(time.RFC3339, stoppedAt)
-		lp.Log.Duration = time.Duration(duration)
 		results = append(results, lp)
 	}
 	return results, nil
 }
 
+// escapeLike escapes the wildcards of a LIKE pattern, for use with
+// ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ErrNoTag is returned when renaming a tag that no log has.
+var ErrNoTag = errors.New("no such tag")
+
+// RenameTag renames a tag on every log that has it. If there already is a
+// tag named to, the two are merged. Names match ignoring case, so a rename
+// can also just change the case.
+func (r *Repository) RenameTag(from, to string) error {
+	var fromID, toID int64
+	err := r.conn().QueryRow("SELECT id FROM tags WHERE name = ?", from).Scan(&fromID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w %q", ErrNoTag, from)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = r.conn().QueryRow("SELECT id FROM tags WHERE name = ?", to).Scan(&toID)
+	if errors.Is(err, sql.ErrNoRows) || toID == fromID {
+		_, err := r.conn().Exec("UPDATE tags SET name = ? WHERE id = ?", to, fromID)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := r.conn().Exec(
+		`INSERT OR IGNORE INTO time_log_tags (time_log_id, tag_id, position)
+		 SELECT time_log_id, ?, position FROM time_log_tags WHERE tag_id = ?`,
+		toID, fromID,
+	); err != nil {
+		return err
+	}
+	// Its links go with it.
+	_, err = r.conn().Exec("DELETE FROM tags WHERE id = ?", fromID)
+	return err
+}
+
+// TagTotal is the logged time of one tag across all projects. A log with
+// several tags counts towards each of them.
+type TagTotal struct {
+	Tag      string
+	Logged   time.Duration
+	Overtime time.Duration
+	Sessions int
+}
+
+// GetTagTotals returns the totals of every tag in use, the most logged
+// first.
+func (r *Repository) GetTagTotals() ([]TagTotal, error) {
+	rows, err := r.conn().Query(
+		`SELECT t.name, SUM(tl.duration), SUM(tl.overtime), COUNT(*)
+		 FROM tags t
+		 JOIN time_log_tags lt ON lt.tag_id = t.id
+		 JOIN time_logs tl ON tl.id = lt.time_log_id
+		 GROUP BY t.id
+		 ORDER BY SUM(tl.duration) DESC, t.name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []TagTotal
+	for rows.Next() {
+		var t TagTotal
+		var logged, overtime int64
+		if err := rows.Scan(&t.Tag, &logged, &overtime, &t.Sessions); err != nil {
+			return nil, err
+		}
+		t.Logged = time.Duration(logged)
+		t.Overtime = time.Duration(overtime)
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+// SuggestTags ranks the tags starting with prefix by how often the project
+// used them, each log counting for less the older it is: half as much after
+// a week. Tags only other projects used follow, ranked the same way.
+func (r *Repository) SuggestTags(projectID int64, prefix string, now time.Time, limit int) ([]string, error) {
+	// The cross join keeps the name index driving the query, so only the
+	// links of matching tags are read.
+	rows, err := r.conn().Query(
+		`SELECT t.name
+		 FROM tags t
+		 CROSS JOIN time_log_tags lt ON lt.tag_id = t.id
+		 JOIN time_logs tl ON tl.id = lt.time_log_id
+		 WHERE t.name LIKE ? ESCAPE '\'
+		 GROUP BY t.name
+		 ORDER BY TOTAL(CASE WHEN tl.project_id = ? THEN 7 / (7 + julianday(?) - julianday(tl.stopped_at)) END) DESC,
+			TOTAL(7 / (7 + julianday(?) - julianday(tl.stopped_at))) DESC,
+			t.name
+		 LIMIT ?`,
+		escapeLike(prefix)+"%", projectID, now.Format(time.RFC3339), now.Format(time.RFC3339), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// ProjectTotal is the logged time of one project, split by budget.
+type ProjectTotal struct {
+	ProjectID   int64
+	ProjectName string
+	MaxTime     time.Duration
+	Logged      time.Duration
+	Overtime    time.Duration
+	Sessions    int
+	Overruns    int // sessions with some overtime
+}
+
+// InBudget returns the logged time that was within the project's budget.
+func (t ProjectTotal) InBudget() time.Duration {
+	return t.Logged - t.Overtime
+}
+
+// GetTotals returns every project's totals, the heaviest overrunners first.
+func (r *Repository) GetTotals() ([]ProjectTotal, error) {
+	rows, err := r.conn().Query(
+		`SELECT p.id, p.name, p.max_time,
+			COALESCE(SUM(tl.duration), 0), COALESCE(SUM(tl.overtime), 0),
+			COUNT(tl.id), COUNT(CASE WHEN tl.overtime > 0 THEN 1 END)
+		 FROM projects p
+		 LEFT JOIN time_logs tl ON tl.project_id = p.id
+		 GROUP BY p.id
+		 ORDER BY COALESCE(SUM(tl.overtime), 0) DESC, p.name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []ProjectTotal
+	for rows.Next() {
+		var t ProjectTotal
+		var maxTime sql.NullInt64
+		var logged, overtime int64
+		if err := rows.Scan(&t.ProjectID, &t.ProjectName, &maxTime, &logged, &overtime, &t.Sessions, &t.Overruns); err != nil {
+			return nil, err
+		}
+		t.MaxTime = time.Duration(maxTime.Int64)
+		t.Logged = time.Duration(logged)
+		t.Overtime = time.Duration(overtime)
+		totals = append(totals, t)
+	}
+	return totals, nil
+}
+
+// StartSession records that a session for the project started at the given
+// instant. The row lives until the session is logged, so a session that was
+// running when the app died can be recovered on the next launch.
+func (r *Repository) StartSession(projectID int64, startedAt time.Time, tag string) error {
+	ts := startedAt.Format(time.RFC3339Nano)
+	_, err := r.conn().Exec(
+		"INSERT OR REPLACE INTO active_sessions (project_id, started_at, last_seen_at, tag) VALUES (?, ?, ?, ?)",
+		projectID, ts, ts, tag,
+	)
+	return err
+}
+
+// TouchSession records that owner, a running TUI, still had the session in
+// progress at the given instant.
+func (r *Repository) TouchSession(projectID int64, owner string, at time.Time) error {
+	_, err := r.conn().Exec(
+		"UPDATE active_sessions SET last_seen_at = ?, owner = ? WHERE project_id = ?",
+		at.Format(time.RFC3339Nano), owner, projectID,
+	)
+	return err
+}
+
+// SetSessionTag changes the tag the session will be logged with if it is
+// stopped without one.
+func (r *Repository) SetSessionTag(projectID int64, tag string) error {
+	_, err := r.conn().Exec("UPDATE active_sessions SET tag = ? WHERE project_id = ?", tag, projectID)
+	return err
+}
+
+// EndSession removes the active session once it has been logged or discarded.
+func (r *Repository) EndSession(projectID int64) error {
+	_, err := r.conn().Exec("DELETE FROM active_sessions WHERE project_id = ?", projectID)
+	return err
+}
+
+func (r *Repository) GetActiveSessions() ([]timelog.ActiveSession, error) {
+	rows, err := r.conn().Query("SELECT project_id, started_at, last_seen_at, tag, owner FROM active_sessions ORDER BY started_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []timelog.ActiveSession
+	for rows.Next() {
+		var s timelog.ActiveSession
+		var startedAt, lastSeenAt string
+		if err := rows.Scan(&s.ProjectID, &startedAt, &lastSeenAt, &s.Tag, &s.Owner); err != nil {
+			return nil, err
+		}
+		s.StartedAt, _ = time.Parse(time.RFC3339Nano, startedAt)
+		s.LastSeenAt, _ = time.Parse(time.RFC3339Nano, lastSeenAt)
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// outboxTime is how outbox times are stored: in UTC and fixed width, so
+// they sort and compare correctly as text. RFC3339Nano drops trailing zeros
+// from the fraction, which puts "10:00:00Z" after "10:00:00.5Z".
+const outboxTime = "2006-01-02T15:04:05.000000000Z07:00"
+
+// OutboxMessage is a webhook delivery waiting to be sent or retried.
+type OutboxMessage struct {
+	ID            int64
+	URL           string
+	Event         string
+	Payload       []byte
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+}
+
+func (r *Repository) EnqueueOutbox(msg *OutboxMessage) error {
+	result, err := r.conn().Exec(
+		`INSERT INTO webhook_outbox (url, event, payload, attempts, next_attempt_at, last_error, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		msg.URL, msg.Event, string(msg.Payload), msg.Attempts,
+		msg.NextAttemptAt.UTC().Format(outboxTime), msg.LastError, msg.CreatedAt.UTC().Format(outboxTime),
+	)
+	if err != nil {
+		return err
+	}
+	msg.ID, err = result.LastInsertId()
+	return err
+}
+
+func (r *Repository) GetOutbox(due time.Time) ([]OutboxMessage, error) {
+	query := "SELECT id, url, event, payload, attempts, next_attempt_at, last_error, created_at FROM webhook_outbox"
+	var args []any
+	if !due.IsZero() {
+		query += " WHERE next_attempt_at <= ?"
+		args = append(args, due.UTC().Format(outboxTime))
+	}
+	rows, err := r.conn().Query(query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		var msg OutboxMessage
+		var payload, nextAttemptAt, createdAt string
+		if err := rows.Scan(&msg.ID, &msg.URL, &msg.Event, &payload, &msg.Attempts, &nextAttemptAt, &msg.LastError, &createdAt); err != nil {
+			return nil, err
+		}
+		msg.Payload = []byte(payload)
+		msg.NextAttemptAt, _ = time.Parse(outboxTime, nextAttemptAt)
+		msg.CreatedAt, _ = time.Parse(outboxTime, createdAt)
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// UpdateOutbox records a failed attempt at delivering msg.
+func (r *Repository) UpdateOutbox(msg *OutboxMessage) error {
+	_, err := r.conn().Exec(
+		"UPDATE webhook_outbox SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?",
+		msg.Attempts, msg.NextAttemptAt.UTC().Format(outboxTime), msg.LastError, msg.ID,
+	)
+	return err
+}
+
+func (r *Repository) DeleteOutbox(id int64) error {
+	_, err := r.conn().Exec("DELETE FROM webhook_outbox WHERE id = ?", id)
+	return err
+}
+
 func (r *Repository) Close() error {
+	r.watch.Close()
 	return r.db.Close()
 }
 
+// formatNullTime stores instants with full precision so a resumed session
+// keeps its sub-second remainder. The zero time is stored as NULL.
+func formatNullTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.Format(time.RFC3339Nano), Valid: true}
+}
+
+func parseNullTime(s sql.NullString) time.Time {
+	if !s.Valid {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339Nano, s.String)
+	return t
+}
+
+// nullDuration stores a zero or negative budget as NULL, meaning none.
+func nullDuration(d time.Duration) sql.NullInt64 {
+	if d <= 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(d), Valid: true}
+}
+
 func ParseDuration(input string) (time.Duration, error) {
 	var d time.Duration
 	_, err := fmt.Sscanf(input, "%d", &d)
@@ -0,0 +1,342 @@
+package project
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"timer_tui/internal/timelog"
+)
+
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	repo, err := NewRepository(filepath.Join(t.TempDir(), "timer_tui.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestActiveSessionLifecycle(t *testing.T) {
+	repo := newTestRepository(t)
+	p, err := repo.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 5, 1, 9, 0, 0, 500, time.UTC)
+	if err := repo.StartSession(p.ID, start, ""); err != nil {
+		t.Fatal(err)
+	}
+	seen := start.Add(time.Minute)
+	if err := repo.TouchSession(p.ID, "tui:1", seen); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := repo.GetActiveSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ProjectID != p.ID || sessions[0].Owner != "tui:1" ||
+		!sessions[0].StartedAt.Equal(start) || !sessions[0].LastSeenAt.Equal(seen) {
+		t.Fatalf("sessions = %+v, want one for %d started %s, seen %s by tui:1", sessions, p.ID, start, seen)
+	}
+
+	if err := repo.EndSession(p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, err = repo.GetActiveSessions(); err != nil || len(sessions) != 0 {
+		t.Fatalf("after end: sessions = %+v, %v; want none", sessions, err)
+	}
+}
+
+func TestDeleteCascadesToLogsAndSessions(t *testing.T) {
+	repo := newTestRepository(t)
+	p, err := repo.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	if err := repo.CreateLog(&timelog.TimeLog{ProjectID: p.ID, StartedAt: start, StoppedAt: start.Add(time.Hour), Duration: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.StartSession(p.ID, start, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Delete(p.ID); err != nil {
+		t.Fatal(err)
+	}
+	var logs, sessions int
+	if err := repo.db.QueryRow("SELECT (SELECT COUNT(*) FROM time_logs), (SELECT COUNT(*) FROM active_sessions)").Scan(&logs, &sessions); err != nil {
+		t.Fatal(err)
+	}
+	if logs != 0 || sessions != 0 {
+		t.Errorf("after delete: %d logs, %d sessions left; want none", logs, sessions)
+	}
+}
+
+func TestWithTxRollsBackOnError(t *testing.T) {
+	repo := newTestRepository(t)
+	failed := errors.New("failed")
+	err := repo.WithTx(func(tx Store) error {
+		if _, err := tx.Create("Write", time.Hour); err != nil {
+			return err
+		}
+		// A nested call joins the outer transaction.
+		return tx.WithTx(func(tx Store) error { return failed })
+	})
+	if err != failed {
+		t.Fatalf("WithTx = %v, want %v", err, failed)
+	}
+	if projects, err := repo.GetAll(); err != nil || len(projects) != 0 {
+		t.Fatalf("after rollback: projects = %+v, %v; want none", projects, err)
+	}
+}
+
+// A project without a budget is stored with a NULL max_time and read back
+// open-ended.
+func TestOpenEndedProjectRoundTrips(t *testing.T) {
+	repo := newTestRepository(t)
+	p, err := repo.Create("Support", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var isNull bool
+	if err := repo.db.QueryRow("SELECT max_time IS NULL FROM projects WHERE id = ?", p.ID).Scan(&isNull); err != nil {
+		t.Fatal(err)
+	}
+	if !isNull {
+		t.Error("max_time is not NULL")
+	}
+
+	stored, err := repo.GetByID(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.Elapsed = 3 * time.Hour
+	if !stored.OpenEnded() || stored.Remaining() != 0 || stored.IsComplete() {
+		t.Errorf("open-ended = %v, remaining %s, complete %v; want open-ended, never complete",
+			stored.OpenEnded(), stored.Remaining(), stored.IsComplete())
+	}
+}
+
+func TestGetTotalsSplitsOvertime(t *testing.T) {
+	for name, store := range map[string]Store{"sqlite": newTestRepository(t), "memory": NewMemoryStore()} {
+		p, err := store.Create("Write", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+		for _, l := range []timelog.TimeLog{
+			{ProjectID: p.ID, StartedAt: start, StoppedAt: start.Add(90 * time.Minute), Duration: 90 * time.Minute, Overtime: 30 * time.Minute},
+			{ProjectID: p.ID, StartedAt: start.Add(2 * time.Hour), StoppedAt: start.Add(140 * time.Minute), Duration: 20 * time.Minute},
+		} {
+			if err := store.CreateLog(&l); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		totals, err := store.GetTotals()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(totals) != 1 {
+			t.Fatalf("%s: totals = %+v, want one", name, totals)
+		}
+		got := totals[0]
+		if got.Logged != 110*time.Minute || got.Overtime != 30*time.Minute || got.InBudget() != 80*time.Minute ||
+			got.Sessions != 2 || got.Overruns != 1 {
+			t.Errorf("%s: total = %+v, want 1h50m logged, 30m over in 1 of 2 sessions", name, got)
+		}
+	}
+}
+
+// A delivery due half a second past a whole second is not due at that
+// second, though RFC 3339 text for the two would compare the other way.
+func TestGetOutboxComparesSubsecondTimes(t *testing.T) {
+	repo := newTestRepository(t)
+
+	second := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	msg := OutboxMessage{
+		URL:           "http://example.com/hook",
+		Event:         "start",
+		Payload:       []byte("{}"),
+		NextAttemptAt: second.Add(500 * time.Millisecond),
+		CreatedAt:     second,
+	}
+	if err := repo.EnqueueOutbox(&msg); err != nil {
+		t.Fatal(err)
+	}
+
+	due, err := repo.GetOutbox(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 0 {
+		t.Fatalf("due at %s: %+v, want none", second, due)
+	}
+
+	due, err = repo.GetOutbox(msg.NextAttemptAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || !due[0].NextAttemptAt.Equal(msg.NextAttemptAt) || !due[0].CreatedAt.Equal(second) {
+		t.Fatalf("due at %s: %+v, want the message", msg.NextAttemptAt, due)
+	}
+}
+
+// A save based on a stale copy of a project is refused, and the other
+// connection notices the change through DataVersion.
+func TestUpdateRejectsStaleRevision(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timer_tui.db")
+	first, err := NewRepository(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := OpenRepository(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	p, err := first.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := second.GetByID(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := second.DataVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Name = "Edit"
+	if err := first.Update(p); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := second.DataVersion(); err != nil || v == version {
+		t.Errorf("data version %d, %v after another connection saved; want it changed from %d", v, err, version)
+	}
+
+	stale.MaxTime = 2 * time.Hour
+	if err := second.Update(stale); !errors.Is(err, ErrConflict) {
+		t.Fatalf("stale update: %v, want ErrConflict", err)
+	}
+	stored, err := second.GetByID(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "Edit" || stored.MaxTime != time.Hour {
+		t.Fatalf("stored %+v, want the first save kept", stored)
+	}
+	if err := second.Update(stored); err != nil {
+		t.Fatalf("update after reload: %v", err)
+	}
+}
+
+// Renaming a tag onto one that exists merges them, and totals count a log
+// towards each of its tags.
+func TestRenameTagMergesAndTotals(t *testing.T) {
+	repo := newTestRepository(t)
+	p, err := repo.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	for i, tags := range [][]string{{"Review", "backend"}, {"code review"}, {"backend"}} {
+		log := timelog.TimeLog{
+			ProjectID: p.ID,
+			StartedAt: start.Add(time.Duration(i) * time.Hour),
+			StoppedAt: start.Add(time.Duration(i)*time.Hour + 30*time.Minute),
+			Duration:  30 * time.Minute,
+			Tags:      tags,
+		}
+		if err := repo.CreateLog(&log); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.RenameTag("code review", "review"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RenameTag("nothing", "else"); !errors.Is(err, ErrNoTag) {
+		t.Errorf("renaming a missing tag: %v, want ErrNoTag", err)
+	}
+
+	totals, err := repo.GetTagTotals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 2 {
+		t.Fatalf("totals = %+v, want backend and Review", totals)
+	}
+	for _, total := range totals {
+		if (total.Tag != "backend" && total.Tag != "Review") || total.Logged != time.Hour || total.Sessions != 2 {
+			t.Errorf("total %+v, want 1h over 2 sessions", total)
+		}
+	}
+
+	logs, err := repo.GetLogsByProject(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range logs {
+		if l.StartedAt.Equal(start) && timelog.FormatTags(l.Tags) != "Review, backend" {
+			t.Errorf("first log tags = %q, want the order kept", l.Tags)
+		}
+	}
+}
+
+// Suggestions put the project's own tags first, recent use outweighing
+// old, then tags only other projects used.
+func TestSuggestTagsRanksByProjectHistory(t *testing.T) {
+	repo := newTestRepository(t)
+	write, err := repo.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := repo.Create("Other", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 5, 31, 9, 0, 0, 0, time.UTC)
+	add := func(p *Project, daysAgo int, tag string) {
+		t.Helper()
+		stop := now.AddDate(0, 0, -daysAgo)
+		log := timelog.TimeLog{ProjectID: p.ID, StartedAt: stop.Add(-time.Minute), StoppedAt: stop, Duration: time.Minute, Tags: []string{tag}}
+		if err := repo.CreateLog(&log); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(write, 28, "research")
+	add(write, 27, "research")
+	add(write, 1, "review")
+	add(other, 0, "release")
+	add(other, 0, "release")
+	add(write, 0, "docs")
+
+	got, err := repo.SuggestTags(write.ID, "re", now, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"review", "research", "release"}
+	if len(got) != len(want) {
+		t.Fatalf("suggestions = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("suggestions = %q, want %q", got, want)
+		}
+	}
+
+	if got, err := repo.SuggestTags(write.ID, "", now, 1); err != nil || len(got) != 1 || got[0] != "docs" {
+		t.Errorf("top suggestion = %q, %v; want docs", got, err)
+	}
+}
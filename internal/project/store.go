@@ -0,0 +1,66 @@
+package project
+
+import (
+	"time"
+
+	"timer_tui/internal/timelog"
+)
+
+// Store persists projects, their time logs and active sessions. Repository
+// is the SQLite implementation; MemoryStore keeps everything in memory.
+type Store interface {
+	GetAll() ([]Project, error)
+	GetByID(id int64) (*Project, error)
+	Create(name string, maxTime time.Duration) (*Project, error)
+	Update(p *Project) error
+	Delete(id int64) error
+	// DataVersion returns a number that changes when another instance
+	// commits to the store, so a caller polling it knows to reload.
+	DataVersion() (int64, error)
+
+	CreateLog(log *timelog.TimeLog) error
+	GetLog(id int64) (*timelog.TimeLog, error)
+	UpdateLog(log *timelog.TimeLog) error
+	DeleteLog(id int64) error
+	GetLogsByProject(projectID int64) ([]timelog.TimeLog, error)
+	GetAllLogs() ([]LogWithProject, error)
+	FindLogs(f LogFilter) ([]LogWithProject, error)
+	// OverlappingLogs returns the logs, other than the one with ID except,
+	// that share some time with from..to.
+	OverlappingLogs(from, to time.Time, except int64) ([]LogWithProject, error)
+	// GetTotals sums each project's logs, splitting in-budget time from
+	// overtime.
+	GetTotals() ([]ProjectTotal, error)
+	// RenameTag renames a tag throughout the logs, merging it into the tag
+	// named to if there is one.
+	RenameTag(from, to string) error
+	GetTagTotals() ([]TagTotal, error)
+	// SuggestTags returns up to limit tags starting with prefix, those the
+	// project used most often and most lately first.
+	SuggestTags(projectID int64, prefix string, now time.Time, limit int) ([]string, error)
+
+	StartSession(projectID int64, startedAt time.Time, tag string) error
+	// TouchSession records that owner, a running TUI, still had the
+	// session in progress at at.
+	TouchSession(projectID int64, owner string, at time.Time) error
+	SetSessionTag(projectID int64, tag string) error
+	EndSession(projectID int64) error
+	GetActiveSessions() ([]timelog.ActiveSession, error)
+
+	EnqueueOutbox(msg *OutboxMessage) error
+	// GetOutbox returns queued webhook deliveries, oldest first. With a
+	// non-zero due, only those due by then are returned.
+	GetOutbox(due time.Time) ([]OutboxMessage, error)
+	UpdateOutbox(msg *OutboxMessage) error
+	DeleteOutbox(id int64) error
+
+	// WithTx runs fn atomically: either all of its writes through tx are
+	// kept or none are.
+	WithTx(fn func(tx Store) error) error
+	Close() error
+}
+
+var (
+	_ Store = (*Repository)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
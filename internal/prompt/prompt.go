@@ -0,0 +1,267 @@
+// Package prompt renders the running timer for shell prompts and status
+// bars. These call it every second, so the stored state is kept in a small
+// cache file and the database is only opened after something wrote to it.
+package prompt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"timer_tui/internal/project"
+	"timer_tui/internal/tracker"
+)
+
+// DefaultFormat shows the running project with the time left in its budget,
+// or the time on it if it has none, and a check mark once the budget is
+// used up.
+const DefaultFormat = `{{if .Running}}{{.Project}} ` +
+	`{{if .OpenEnded}}{{.Elapsed}}{{else if .Complete}}+{{.Overtime}} ✓{{else}}{{.Remaining}}{{end}}` +
+	`{{end}}`
+
+// Data is what a format template is executed with. Nothing is running when
+// Running is false, and the other fields are then empty.
+type Data struct {
+	Running   bool
+	Project   string
+	Tag       string
+	Elapsed   Duration // time on the project, including the running session
+	Session   Duration // time since the running session started
+	Remaining Duration // time left in the budget
+	Overtime  Duration // time past the budget
+	OpenEnded bool     // the project has no budget
+	Complete  bool     // the budget is used up
+	Phase     string   // pomodoro phase, such as "Work" or "Short break"
+	PhaseLeft Duration // time left in the pomodoro phase
+}
+
+// Duration prints as MM:SS, or H:MM:SS from an hour up.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	total := int(time.Duration(d).Seconds())
+	if total < 0 {
+		total = 0
+	}
+	if h := total / 3600; h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, total%3600/60, total%60)
+	}
+	return fmt.Sprintf("%02d:%02d", total/60, total%60)
+}
+
+// Minutes returns the whole minutes in d, for formats that show only those.
+func (d Duration) Minutes() int {
+	return int(time.Duration(d).Minutes())
+}
+
+// Render writes the running timer of the database at dbPath to out in the
+// given format, followed by a newline unless nothing was rendered.
+// cachePath is where the stored state is cached between calls.
+func Render(out io.Writer, dbPath, cachePath, format string, now time.Time) error {
+	tmpl, err := template.New("prompt").Parse(format)
+	if err != nil {
+		return err
+	}
+	c, err := cached(dbPath, cachePath)
+	if err != nil {
+		return err
+	}
+	data, err := c.data(now)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	if buf.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+	_, err = buf.WriteTo(out)
+	return err
+}
+
+// stamp identifies the state of a database file. The database is written
+// to through its WAL, which is checkpointed into the main file now and
+// then, so both are looked at.
+type stamp struct {
+	DB  fileStamp `json:"db"`
+	WAL fileStamp `json:"wal"`
+}
+
+type fileStamp struct {
+	ModTime int64 `json:"mod_time"`
+	Size    int64 `json:"size"`
+}
+
+func stampOf(dbPath string) stamp {
+	return stamp{DB: fileStampOf(dbPath), WAL: fileStampOf(dbPath + "-wal")}
+}
+
+func fileStampOf(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+}
+
+// cache is the stored state of the running project, as of Stamp.
+type cache struct {
+	Stamp   stamp            `json:"stamp"`
+	Project *project.Project `json:"project,omitempty"` // nil when none is running
+	Tag     string           `json:"tag,omitempty"`
+}
+
+// cached returns the cache for the database, reloading it if the database
+// changed since it was written.
+func cached(dbPath, cachePath string) (*cache, error) {
+	st := stampOf(dbPath)
+	if b, err := os.ReadFile(cachePath); err == nil {
+		var c cache
+		if json.Unmarshal(b, &c) == nil && c.Stamp == st {
+			return &c, nil
+		}
+	}
+
+	c, err := load(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	// The stamp from before reading may be older than what was read, which
+	// only means the next call reads again.
+	c.Stamp = st
+	save(cachePath, c)
+	return c, nil
+}
+
+func load(dbPath string) (*cache, error) {
+	if _, err := os.Stat(dbPath); errors.Is(err, os.ErrNotExist) {
+		return &cache{}, nil
+	}
+	repo, err := project.OpenReadOnly(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Close()
+
+	// A database an older build wrote lacks columns read below. Nothing is
+	// shown until a command that opens it for writing migrates it.
+	if ok, err := repo.UpToDate(); err != nil || !ok {
+		return &cache{}, err
+	}
+
+	projects, err := repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	c := &cache{}
+	for i := range projects {
+		if projects[i].Running {
+			c.Project = &projects[i]
+			break
+		}
+	}
+	if c.Project == nil {
+		return c, nil
+	}
+
+	c.Tag = c.Project.PomodoroTag
+	sessions, err := repo.GetActiveSessions()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		if s.ProjectID == c.Project.ID && s.Tag != "" {
+			c.Tag = s.Tag
+		}
+	}
+	return c, nil
+}
+
+// save writes the cache through a temporary file, so a concurrent call
+// never reads half of it. Failing to cache only makes later calls slower.
+func save(cachePath string, c *cache) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(cachePath), filepath.Base(cachePath)+".*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), cachePath); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// data works out the live values at now from the stored state.
+func (c *cache) data(now time.Time) (Data, error) {
+	if c.Project == nil {
+		return Data{}, nil
+	}
+	p := c.Project
+	if p.Pomodoro != nil {
+		// Phases that ended since the state was stored are played forward
+		// as the TUI would, in a scratch store so nothing is written.
+		var err error
+		if p, err = advance(p, now); err != nil {
+			return Data{}, err
+		}
+	}
+
+	elapsed := p.ElapsedAt(now)
+	d := Data{
+		Running:   true,
+		Project:   p.Name,
+		Tag:       c.Tag,
+		Elapsed:   Duration(elapsed),
+		Overtime:  Duration(p.OverBudget(0, elapsed)),
+		OpenEnded: p.OpenEnded(),
+		Complete:  !p.OpenEnded() && elapsed >= p.MaxTime,
+	}
+	if !d.OpenEnded && !d.Complete {
+		d.Remaining = Duration(p.MaxTime - elapsed)
+	}
+	if !p.StartedAt.IsZero() {
+		d.Session = Duration(now.Sub(p.StartedAt))
+	}
+	if p.Pomodoro != nil {
+		d.Phase = p.Phase.String()
+		d.PhaseLeft = Duration(p.PhaseEnd().Sub(now))
+	}
+	return d, nil
+}
+
+func advance(stored *project.Project, now time.Time) (*project.Project, error) {
+	scratch := project.NewMemoryStore()
+	created, err := scratch.Create(stored.Name, stored.MaxTime)
+	if err != nil {
+		return nil, err
+	}
+	p := *stored
+	p.ID, p.Revision = created.ID, created.Revision
+	if err := scratch.Update(&p); err != nil {
+		return nil, err
+	}
+	advanced, _, err := tracker.Advance(scratch, p.ID, now)
+	return advanced, err
+}
@@ -0,0 +1,108 @@
+package prompt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"timer_tui/internal/project"
+	"timer_tui/internal/tracker"
+)
+
+func render(t *testing.T, dbPath, cachePath, format string, now time.Time) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Render(&out, dbPath, cachePath, format, now); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	dbPath, cachePath := filepath.Join(dir, "timer_tui.db"), filepath.Join(dir, "cache", "prompt.json")
+	if got := render(t, dbPath, cachePath, DefaultFormat, time.Now()); got != "" {
+		t.Fatalf("without a database: %q, want nothing", got)
+	}
+
+	repo, err := project.NewRepository(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := repo.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := render(t, dbPath, cachePath, DefaultFormat, time.Now()); got != "" {
+		t.Fatalf("nothing running: %q, want nothing", got)
+	}
+
+	start := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	err = repo.WithTx(func(tx project.Store) error {
+		_, _, err := tracker.Start(tx, p.ID, start, "docs")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	now := start.Add(10 * time.Minute)
+	if got := render(t, dbPath, cachePath, DefaultFormat, now); got != "Write 50:00\n" {
+		t.Errorf("running: %q, want \"Write 50:00\\n\"", got)
+	}
+	if got := render(t, dbPath, cachePath, "{{.Tag}} {{.Session}} {{.Remaining.Minutes}}", now); got != "docs 10:00 50\n" {
+		t.Errorf("custom format: %q", got)
+	}
+	if got := render(t, dbPath, cachePath, DefaultFormat, start.Add(70*time.Minute)); got != "Write +10:00 ✓\n" {
+		t.Errorf("over budget: %q, want \"Write +10:00 ✓\\n\"", got)
+	}
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Errorf("no cache written: %v", err)
+	}
+}
+
+// A database not yet migrated to this build's schema shows nothing rather
+// than failing on the columns it lacks.
+func TestRenderBeforeMigrating(t *testing.T) {
+	dir := t.TempDir()
+	dbPath, cachePath := filepath.Join(dir, "timer_tui.db"), filepath.Join(dir, "cache", "prompt.json")
+	repo, err := project.NewRepository(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := repo.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.WithTx(func(tx project.Store) error {
+		_, _, err := tracker.Start(tx, p.ID, time.Now(), "")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.MigrateDown(); err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	if got := render(t, dbPath, cachePath, DefaultFormat, time.Now()); got != "" {
+		t.Errorf("older schema: %q, want nothing", got)
+	}
+}
+
+func TestDurationString(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		-time.Second:     "00:00",
+		59 * time.Second: "00:59",
+		25 * time.Minute: "25:00",
+		time.Hour + 2*time.Minute + 3*time.Second: "1:02:03",
+	} {
+		if got := Duration(d).String(); got != want {
+			t.Errorf("Duration(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,637 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"timer_tui/internal/config"
+	"timer_tui/internal/hooks"
+	"timer_tui/internal/project"
+	"timer_tui/internal/timelog"
+	"timer_tui/internal/tracker"
+)
+
+// Server is a JSON API over HTTP for scripts and other tools. It applies the
+// same start/stop rules as the TUI and the subcommands through the tracker.
+//
+//	GET    /projects               list projects
+//	POST   /projects               create {"name", "max_time_seconds"}
+//	GET    /projects/{id}          show a project
+//	PATCH  /projects/{id}          change {"name", "max_time_seconds"}
+//	DELETE /projects/{id}          delete a project and its logs
+//	POST   /projects/{id}/start    start its timer {"tag"}
+//	POST   /projects/{id}/stop     stop its timer and log it {"tag"}
+//	GET    /logs                   ?project=ID&from=DATE&to=DATE
+//
+// Every request must carry the token as "Authorization: Bearer TOKEN" and
+// name the listen address as its Host, and those that write must send
+// JSON. A web page can then neither drive the API nor read from it.
+type Server struct {
+	store  project.Store
+	events Events
+	addr   string
+	token  string
+
+	// mu serializes requests that write, so that a read-modify-write of a
+	// project does not interleave with another.
+	mu sync.Mutex
+}
+
+// NewServer returns a server for the API listening on addr, which accepts
+// requests bearing token.
+func NewServer(store project.Store, ev Events, addr, token string) *Server {
+	return &Server{store: store, events: ev, addr: addr, token: token}
+}
+
+// runServe serves the API until interrupted, delivering webhooks in the
+// background meanwhile.
+func runServe(store project.Store, ev Events, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:7777", "address to listen on")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return errors.New("usage: timer_tui serve [--addr HOST:PORT]")
+	}
+	tokenPath, err := config.APITokenPath()
+	if err != nil {
+		return err
+	}
+	token, err := loadToken(tokenPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go ev.Webhooks.Run(ctx)
+
+	srv := &http.Server{Addr: *addr, Handler: NewServer(store, ev, *addr, token)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(out, "Listening on http://%s with the token in %s\n", *addr, tokenPath)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// loadToken reads the API token from path, creating the file with a random
+// token if there is none. The file must be readable by its owner alone.
+func loadToken(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return createToken(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf("%s is readable by others; chmod 600 it", path)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return token, nil
+}
+
+func createToken(path string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	_, err = fmt.Fprintln(f, token)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return token, nil
+}
+
+// apiError is an error with the HTTP status it is reported with.
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+func badRequest(format string, args ...any) error {
+	return &apiError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+type projectJSON struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	MaxTime   *int64     `json:"max_time_seconds"` // null for an open-ended project
+	Elapsed   int64      `json:"elapsed_seconds"`
+	Overtime  int64      `json:"overtime_seconds"`
+	Running   bool       `json:"running"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Pomodoro  string     `json:"pomodoro,omitempty"`
+	Phase     string     `json:"phase,omitempty"`
+}
+
+func toProjectJSON(p *project.Project, now time.Time) projectJSON {
+	elapsed := p.ElapsedAt(now)
+	j := projectJSON{
+		ID:       p.ID,
+		Name:     p.Name,
+		Elapsed:  seconds(elapsed),
+		Overtime: seconds(p.OverBudget(0, elapsed)),
+		Running:  p.Running,
+		Phase:    string(p.Phase),
+	}
+	if !p.OpenEnded() {
+		max := seconds(p.MaxTime)
+		j.MaxTime = &max
+	}
+	if !p.StartedAt.IsZero() {
+		j.StartedAt = &p.StartedAt
+	}
+	if p.Pomodoro != nil {
+		j.Pomodoro = p.Pomodoro.String()
+	}
+	return j
+}
+
+type logJSON struct {
+	ID        int64     `json:"id"`
+	ProjectID int64     `json:"project_id"`
+	Project   string    `json:"project"`
+	StartedAt time.Time `json:"started_at"`
+	StoppedAt time.Time `json:"stopped_at"`
+	Duration  int64     `json:"duration_seconds"`
+	Overtime  int64     `json:"overtime_seconds"`
+	Tag       string    `json:"tag"`
+	Tags      []string  `json:"tags"`
+	Phase     string    `json:"phase,omitempty"`
+	Note      string    `json:"note"`
+}
+
+func toLogJSON(lp project.LogWithProject) logJSON {
+	return logJSON{
+		ID:        lp.Log.ID,
+		ProjectID: lp.Log.ProjectID,
+		Project:   lp.ProjectName,
+		StartedAt: lp.Log.StartedAt,
+		StoppedAt: lp.Log.StoppedAt,
+		Duration:  seconds(lp.Log.Duration),
+		Overtime:  seconds(lp.Log.Overtime),
+		Tag:       timelog.FormatTags(lp.Log.Tags),
+		Tags:      append([]string{}, lp.Log.Tags...),
+		Phase:     lp.Log.Phase,
+		Note:      lp.Log.Note,
+	}
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(d / time.Second)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var v any
+	err := s.check(r)
+	if err == nil {
+		v, err = s.route(r)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		var apiErr *apiError
+		switch {
+		case errors.As(err, &apiErr):
+			status = apiErr.status
+		case errors.Is(err, sql.ErrNoRows):
+			status, err = http.StatusNotFound, errors.New("project not found")
+		case errors.Is(err, tracker.ErrAlreadyRunning), errors.Is(err, tracker.ErrNotRunning):
+			status = http.StatusConflict
+		}
+		if status == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	if v == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	status := http.StatusOK
+	if r.Method == http.MethodPost && r.URL.Path == "/projects" {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// check refuses requests that do not come from a client holding the token.
+// Checking the Host keeps pages on other sites from reaching the API under
+// a DNS name of their own, and only JSON bodies are accepted because forms
+// cannot send them.
+func (s *Server) check(r *http.Request) error {
+	if !s.hostAllowed(r.Host) {
+		return &apiError{http.StatusForbidden, fmt.Sprintf("host %q is not the listen address", r.Host)}
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+		return &apiError{http.StatusUnauthorized, "missing or wrong bearer token"}
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			return &apiError{http.StatusUnsupportedMediaType, "Content-Type must be application/json"}
+		}
+	}
+	return nil
+}
+
+// hostAllowed reports whether host, from a request, names the listen
+// address. On a loopback address "localhost" does too, and when listening
+// on every interface any address with the right port does, but no other
+// name.
+func (s *Server) hostAllowed(host string) bool {
+	if host == s.addr {
+		return true
+	}
+	name, port, err := net.SplitHostPort(host)
+	listenHost, listenPort, lerr := net.SplitHostPort(s.addr)
+	if err != nil || lerr != nil || port != listenPort {
+		return false
+	}
+	ip := net.ParseIP(listenHost)
+	switch {
+	case listenHost == "" || ip != nil && ip.IsUnspecified():
+		return net.ParseIP(name) != nil
+	case ip != nil && ip.IsLoopback():
+		return name == "localhost"
+	}
+	return false
+}
+
+// route dispatches a request and returns the value to respond with, or nil
+// for an empty response.
+func (s *Server) route(r *http.Request) (any, error) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "projects":
+		switch r.Method {
+		case http.MethodGet:
+			return s.listProjects()
+		case http.MethodPost:
+			return s.createProject(r)
+		}
+	case len(parts) == 2 && parts[0] == "projects":
+		id, err := parseID(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		switch r.Method {
+		case http.MethodGet:
+			return s.getProject(id)
+		case http.MethodPatch:
+			return s.updateProject(id, r)
+		case http.MethodDelete:
+			return nil, s.deleteProject(id)
+		}
+	case len(parts) == 3 && parts[0] == "projects" && (parts[2] == "start" || parts[2] == "stop"):
+		id, err := parseID(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if r.Method == http.MethodPost {
+			if parts[2] == "start" {
+				return s.startProject(id, r)
+			}
+			return s.stopProject(id, r)
+		}
+	case len(parts) == 1 && parts[0] == "logs":
+		if r.Method == http.MethodGet {
+			return s.listLogs(r)
+		}
+	case len(parts) == 1 && parts[0] == "tags":
+		if r.Method == http.MethodGet {
+			return s.listTags()
+		}
+	default:
+		return nil, &apiError{http.StatusNotFound, "not found"}
+	}
+	return nil, &apiError{http.StatusMethodNotAllowed, r.Method + " is not allowed on " + r.URL.Path}
+}
+
+func parseID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, &apiError{http.StatusNotFound, "project not found"}
+	}
+	return id, nil
+}
+
+// decodeBody decodes a JSON request body into v. An empty body leaves v
+// unchanged.
+func decodeBody(r *http.Request, v any) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return badRequest("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) listProjects() (any, error) {
+	projects, err := s.store.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	results := make([]projectJSON, len(projects))
+	for i := range projects {
+		results[i] = toProjectJSON(&projects[i], now)
+	}
+	return results, nil
+}
+
+func (s *Server) getProject(id int64) (any, error) {
+	p, err := s.store.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return toProjectJSON(p, time.Now()), nil
+}
+
+// projectBody is the body of a create or update. MaxTime is left as raw JSON
+// so that an explicit null, which makes a project open-ended, can be told
+// apart from leaving the budget out of an update.
+type projectBody struct {
+	Name    *string         `json:"name"`
+	MaxTime json.RawMessage `json:"max_time_seconds"`
+}
+
+func (b projectBody) maxTime() (time.Duration, error) {
+	if len(b.MaxTime) == 0 || string(b.MaxTime) == "null" {
+		return 0, nil
+	}
+	var secs int64
+	if err := json.Unmarshal(b.MaxTime, &secs); err != nil || secs < 0 {
+		return 0, badRequest("max_time_seconds must be a non-negative integer or null")
+	}
+	return time.Duration(secs) * time.Second, nil
+}
+
+func (s *Server) createProject(r *http.Request) (any, error) {
+	var body projectBody
+	if err := decodeBody(r, &body); err != nil {
+		return nil, err
+	}
+	if body.Name == nil || strings.TrimSpace(*body.Name) == "" {
+		return nil, badRequest("name is required")
+	}
+	maxTime, err := body.maxTime()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.store.Create(strings.TrimSpace(*body.Name), maxTime)
+	if err != nil {
+		return nil, err
+	}
+	return toProjectJSON(p, time.Now()), nil
+}
+
+func (s *Server) updateProject(id int64, r *http.Request) (any, error) {
+	var body projectBody
+	if err := decodeBody(r, &body); err != nil {
+		return nil, err
+	}
+	if body.Name != nil && strings.TrimSpace(*body.Name) == "" {
+		return nil, badRequest("name cannot be empty")
+	}
+	maxTime, err := body.maxTime()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var updated *project.Project
+	err = s.store.WithTx(func(tx project.Store) error {
+		p, err := tx.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if body.Name != nil {
+			p.Name = strings.TrimSpace(*body.Name)
+		}
+		if body.MaxTime != nil {
+			p.MaxTime = maxTime
+		}
+		updated = p
+		return tx.Update(p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toProjectJSON(updated, time.Now()), nil
+}
+
+func (s *Server) deleteProject(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.store.GetByID(id); err != nil {
+		return err
+	}
+	return s.store.Delete(id)
+}
+
+type tagBody struct {
+	Tag string `json:"tag"`
+}
+
+type startResponse struct {
+	Project projectJSON   `json:"project"`
+	Stopped []projectJSON `json:"stopped"`
+}
+
+func (s *Server) startProject(id int64, r *http.Request) (any, error) {
+	var body tagBody
+	if err := decodeBody(r, &body); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var started *project.Project
+	var stopped []tracker.Stopped
+	var events []hooks.Payload
+	err := s.store.WithTx(func(tx project.Store) error {
+		var err error
+		if started, stopped, err = tracker.Start(tx, id, now, body.Tag); err != nil {
+			return err
+		}
+		payload := hooks.ProjectEvent(hooks.Start, started, now)
+		payload.Tag = body.Tag
+		events = append(stoppedEvents(stopped, now), payload)
+		return s.events.queue(tx, events)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.events.fire(events...)
+
+	resp := startResponse{Project: toProjectJSON(started, now), Stopped: []projectJSON{}}
+	for _, st := range stopped {
+		resp.Stopped = append(resp.Stopped, toProjectJSON(st.Project, now))
+	}
+	return resp, nil
+}
+
+type stopResponse struct {
+	Project projectJSON `json:"project"`
+	Logs    []logJSON   `json:"logs"`
+}
+
+func (s *Server) stopProject(id int64, r *http.Request) (any, error) {
+	var body tagBody
+	if err := decodeBody(r, &body); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var stopped tracker.Stopped
+	err := s.store.WithTx(func(tx project.Store) error {
+		var err error
+		if stopped, err = tracker.Stop(tx, id, now, body.Tag); err != nil {
+			return err
+		}
+		return s.events.queue(tx, stoppedEvents([]tracker.Stopped{stopped}, now))
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.events.fire(stoppedEvents([]tracker.Stopped{stopped}, now)...)
+
+	resp := stopResponse{Project: toProjectJSON(stopped.Project, now), Logs: []logJSON{}}
+	for _, l := range stopped.Logs {
+		resp.Logs = append(resp.Logs, toLogJSON(project.LogWithProject{Log: l, ProjectName: stopped.Project.Name}))
+	}
+	return resp, nil
+}
+
+func (s *Server) listLogs(r *http.Request) (any, error) {
+	q := r.URL.Query()
+	var f project.LogFilter
+	if ref := q.Get("project"); ref != "" {
+		id, err := strconv.ParseInt(ref, 10, 64)
+		if err != nil {
+			return nil, badRequest("project must be a project ID")
+		}
+		f.ProjectID = id
+	}
+	var err error
+	if f.From, err = parseBound(q.Get("from"), false); err != nil {
+		return nil, err
+	}
+	if f.To, err = parseBound(q.Get("to"), true); err != nil {
+		return nil, err
+	}
+	f.Search = q.Get("q")
+
+	logs, err := s.store.FindLogs(f)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]logJSON, len(logs))
+	for i, lp := range logs {
+		results[i] = toLogJSON(lp)
+	}
+	return results, nil
+}
+
+type tagJSON struct {
+	Tag      string `json:"tag"`
+	Sessions int    `json:"sessions"`
+	Logged   int64  `json:"logged_seconds"`
+	Overtime int64  `json:"overtime_seconds"`
+}
+
+func (s *Server) listTags() (any, error) {
+	totals, err := s.store.GetTagTotals()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]tagJSON, len(totals))
+	for i, t := range totals {
+		results[i] = tagJSON{Tag: t.Tag, Sessions: t.Sessions, Logged: seconds(t.Logged), Overtime: seconds(t.Overtime)}
+	}
+	return results, nil
+}
+
+// parseBound parses a from or to query parameter, either an RFC 3339 time or
+// a local date. A date given as the end of a range includes that whole day.
+func parseBound(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, badRequest("invalid date %q: use YYYY-MM-DD or RFC 3339", s)
+	}
+	if end {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d, nil
+}
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"timer_tui/internal/project"
+)
+
+const (
+	testAddr  = "127.0.0.1:7777"
+	testToken = "secret"
+)
+
+func newTestServer() *Server {
+	return NewServer(project.NewMemoryStore(), Events{}, testAddr, testToken)
+}
+
+// newRequest makes a request as a client of the API sends it: to the
+// listen address, with the token, and as JSON.
+func newRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Host = testAddr
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req
+}
+
+// serve sends a request to srv and decodes the JSON response into v, if
+// given, returning the status.
+func serve(t *testing.T, srv http.Handler, method, path, body string, v any) int {
+	t.Helper()
+	req := newRequest(method, path, body)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: %v in %q", method, path, err, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+func TestServerProjectLifecycle(t *testing.T) {
+	srv := newTestServer()
+
+	var created projectJSON
+	if status := serve(t, srv, "POST", "/projects", `{"name": "Write", "max_time_seconds": 3600}`, &created); status != http.StatusCreated {
+		t.Fatalf("create: status %d", status)
+	}
+	if created.Name != "Write" || created.MaxTime == nil || *created.MaxTime != 3600 {
+		t.Fatalf("created %+v, want Write with an hour", created)
+	}
+
+	var open projectJSON
+	serve(t, srv, "POST", "/projects", `{"name": "Support", "max_time_seconds": null}`, &open)
+	if open.MaxTime != nil {
+		t.Errorf("created %+v, want open-ended", open)
+	}
+
+	var started startResponse
+	if status := serve(t, srv, "POST", "/projects/1/start", `{"tag": "draft"}`, &started); status != http.StatusOK || !started.Project.Running {
+		t.Fatalf("start: status %d, %+v", status, started)
+	}
+	if status := serve(t, srv, "POST", "/projects/1/start", "", nil); status != http.StatusConflict {
+		t.Errorf("second start: status %d, want conflict", status)
+	}
+
+	// Starting another project stops the first.
+	serve(t, srv, "POST", "/projects/2/start", "", &started)
+	if len(started.Stopped) != 1 || started.Stopped[0].ID != 1 {
+		t.Fatalf("start Support stopped %+v, want Write", started.Stopped)
+	}
+
+	var stopped stopResponse
+	if status := serve(t, srv, "POST", "/projects/2/stop", `{"tag": "tickets"}`, &stopped); status != http.StatusOK {
+		t.Fatalf("stop: status %d", status)
+	}
+	if len(stopped.Logs) != 1 || stopped.Logs[0].Tag != "tickets" {
+		t.Errorf("stop logged %+v, want one tagged tickets", stopped.Logs)
+	}
+
+	var logs []logJSON
+	serve(t, srv, "GET", "/logs?project=1", "", &logs)
+	if len(logs) != 1 || logs[0].Tag != "draft" || logs[0].Project != "Write" {
+		t.Errorf("logs = %+v, want Write's session tagged draft", logs)
+	}
+
+	var renamed projectJSON
+	serve(t, srv, "PATCH", "/projects/1", `{"name": "Edit"}`, &renamed)
+	if renamed.Name != "Edit" || renamed.MaxTime == nil || *renamed.MaxTime != 3600 {
+		t.Errorf("renamed %+v, want Edit keeping its budget", renamed)
+	}
+
+	if status := serve(t, srv, "DELETE", "/projects/1", "", nil); status != http.StatusNoContent {
+		t.Errorf("delete: status %d", status)
+	}
+	if status := serve(t, srv, "GET", "/projects/1", "", nil); status != http.StatusNotFound {
+		t.Errorf("get deleted: status %d, want not found", status)
+	}
+}
+
+func TestServerRejectsBadRequests(t *testing.T) {
+	srv := newTestServer()
+	for _, tc := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{"POST", "/projects", `{"name": ""}`, http.StatusBadRequest},
+		{"POST", "/projects", `{"name": "X", "max_time_seconds": -1}`, http.StatusBadRequest},
+		{"POST", "/projects", `{`, http.StatusBadRequest},
+		{"PUT", "/projects", "", http.StatusMethodNotAllowed},
+		{"GET", "/projects/abc", "", http.StatusNotFound},
+		{"POST", "/projects/1/stop", "", http.StatusNotFound},
+		{"GET", "/logs?from=yesterday", "", http.StatusBadRequest},
+		{"GET", "/nothing", "", http.StatusNotFound},
+	} {
+		var resp map[string]string
+		if status := serve(t, srv, tc.method, tc.path, tc.body, &resp); status != tc.want || resp["error"] == "" {
+			t.Errorf("%s %s %s: status %d, %v; want %d with an error", tc.method, tc.path, tc.body, status, resp, tc.want)
+		}
+	}
+}
+
+// Requests without the token, for another host or writing something other
+// than JSON are refused before anything is done.
+func TestServerRejectsUnauthorizedRequests(t *testing.T) {
+	srv := newTestServer()
+	for name, tc := range map[string]struct {
+		edit func(*http.Request)
+		want int
+	}{
+		"no token":    {func(r *http.Request) { r.Header.Del("Authorization") }, http.StatusUnauthorized},
+		"wrong token": {func(r *http.Request) { r.Header.Set("Authorization", "Bearer guess") }, http.StatusUnauthorized},
+		"other host":  {func(r *http.Request) { r.Host = "attacker.example:7777" }, http.StatusForbidden},
+		"other port":  {func(r *http.Request) { r.Host = "localhost:8080" }, http.StatusForbidden},
+		"form post":   {func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") }, http.StatusUnsupportedMediaType},
+		"no type":     {func(r *http.Request) { r.Header.Del("Content-Type") }, http.StatusUnsupportedMediaType},
+	} {
+		req := newRequest("POST", "/projects", `{"name": "Write"}`)
+		tc.edit(req)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", name, rec.Code, tc.want)
+		}
+	}
+
+	var projects []projectJSON
+	serve(t, srv, "GET", "/projects", "", &projects)
+	if len(projects) != 0 {
+		t.Fatalf("projects = %+v, want none created", projects)
+	}
+
+	req := newRequest("POST", "/projects", `{"name": "Write"}`)
+	req.Host = "localhost:7777"
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("localhost with a charset: status %d, want created", rec.Code)
+	}
+}
+
+// The token is created on first use, readable by its owner alone, and a
+// token file others can read is refused.
+func TestLoadToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "api_token")
+	token, err := loadToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 64 || info.Mode().Perm() != 0o600 {
+		t.Fatalf("token %q in a file with mode %v, want 64 hex digits and 0600", token, info.Mode().Perm())
+	}
+	if again, err := loadToken(path); err != nil || again != token {
+		t.Errorf("second load = %q, %v; want the same token", again, err)
+	}
+
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadToken(path); err == nil {
+		t.Error("loaded a token others can read")
+	}
+}
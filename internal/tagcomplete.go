@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"strings"
+	"time"
+
+	"timer_tui/internal/timelog"
+)
+
+// maxTagSuggestions is how many suggestions the tag prompt shows.
+const maxTagSuggestions = 5
+
+// TagCompletion is the list of suggestions for the tag being typed at the
+// tag prompt. Typed is the input as typed, which Tab replaces the last tag
+// of with suggestion Choice; -1 leaves it as typed.
+type TagCompletion struct {
+	Suggestions []string
+	Typed       string
+	Choice      int
+}
+
+// splitTagInput splits tag prompt input into the tags already written and
+// the one being typed, which follows the last '#' or comma.
+func splitTagInput(input string) (head, last string) {
+	i := strings.LastIndexAny(input, "#,") + 1
+	last = strings.TrimLeft(input[i:], " ")
+	return input[:len(input)-len(last)], last
+}
+
+// suggestTags looks up suggestions for the tag being typed, ranked by the
+// pending log's project's history, leaving out tags already written.
+func (m *Model) suggestTags() {
+	m.TagCompletion = TagCompletion{Typed: m.TagInput, Choice: -1}
+	if m.PendingLog == nil {
+		return
+	}
+	head, last := splitTagInput(m.TagInput)
+	written := timelog.ParseTags(head)
+	tags, err := m.store.SuggestTags(m.PendingLog.ProjectID, last, time.Now(), maxTagSuggestions+len(written))
+	if err != nil {
+		m.Err = err
+		return
+	}
+	for _, tag := range tags {
+		if len(m.TagCompletion.Suggestions) == maxTagSuggestions {
+			break
+		}
+		if !containsTag(written, tag) {
+			m.TagCompletion.Suggestions = append(m.TagCompletion.Suggestions, tag)
+		}
+	}
+}
+
+// cycleTag puts the next suggestion in place of the tag being typed, or the
+// previous one for a negative step. It reports false when no tag is being
+// typed, when there is nothing to suggest, and when it comes back round to
+// the input as typed, which it restores, so Tab moves on to the note.
+func (m *Model) cycleTag(step int) bool {
+	c := &m.TagCompletion
+	n := len(c.Suggestions)
+	head, last := splitTagInput(c.Typed)
+	if n == 0 || last == "" {
+		return false
+	}
+	c.Choice = (c.Choice+1+step+n+1)%(n+1) - 1
+	if c.Choice < 0 {
+		m.TagInput = c.Typed
+		return false
+	}
+	m.TagInput = head + c.Suggestions[c.Choice]
+	return true
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,9 @@
 package timelog
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // TimeLog represents a recorded timer session for a project.
 type TimeLog struct {
@@ -9,5 +12,44 @@ type TimeLog struct {
 	StartedAt time.Time
 	StoppedAt time.Time
 	Duration  time.Duration
-	Tag       string
+	Tags      []string
+	Phase     string        // pomodoro phase the session was logged for, if any
+	Overtime  time.Duration // part of Duration spent past the project's budget
+	Note      string        // free text about the session, possibly several lines
+}
+
+// ActiveSession is a timer session that has started but has not been logged
+// yet. It is persisted so a session survives a crash of the app.
+type ActiveSession struct {
+	ProjectID  int64
+	StartedAt  time.Time
+	LastSeenAt time.Time // last time the running app confirmed the session
+	Tag        string    // tag given at start, used if the stop gives none
+	// Owner is the TUI running the session, which confirms it every so
+	// often. It is empty for sessions started from the command line or the
+	// HTTP API, which nothing confirms.
+	Owner string
+}
+
+// ParseTags reads the tags typed for a session, such as "#review #backend"
+// or "review, backend". Tags are separated by "#" and commas, so a tag can
+// hold spaces but neither of those. Repeats are dropped, ignoring case.
+func ParseTags(s string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.FieldsFunc(s, func(r rune) bool { return r == '#' || r == ',' }) {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// FormatTags writes tags in a form ParseTags reads back.
+func FormatTags(tags []string) string {
+	return strings.Join(tags, ", ")
 }
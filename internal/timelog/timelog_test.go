@@ -0,0 +1,24 @@
+package timelog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	for in, want := range map[string][]string{
+		"":                        nil,
+		"review":                  {"review"},
+		"#review #backend":        {"review", "backend"},
+		"code review, backend, ":  {"code review", "backend"},
+		"#Review, review #REVIEW": {"Review"},
+	} {
+		got := ParseTags(in)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseTags(%q) = %q, want %q", in, got, want)
+		}
+		if back := ParseTags(FormatTags(got)); !reflect.DeepEqual(back, want) {
+			t.Errorf("ParseTags(FormatTags(%q)) = %q", got, back)
+		}
+	}
+}
@@ -5,22 +5,26 @@ import (
 	"time"
 )
 
+// Timer derives elapsed time from the instant it was started rather than by
+// counting ticks, so time spent suspended or descheduled is never lost.
 type Timer struct {
-	mu       sync.RWMutex
-	elapsed  time.Duration
-	running  bool
-	interval time.Duration
-	stopChan chan struct{}
+	mu        sync.RWMutex
+	base      time.Duration // elapsed time banked before the current run
+	startedAt time.Time     // start of the current run, zero when stopped
+	running   bool
 }
 
 func New() *Timer {
-	return &Timer{
-		interval: time.Second,
-		stopChan: make(chan struct{}),
-	}
+	return &Timer{}
 }
 
 func (t *Timer) Start() {
+	t.StartAt(time.Now())
+}
+
+// StartAt starts the timer as if it had been started at the given instant.
+// It is used to resume a run that began in a previous process.
+func (t *Timer) StartAt(at time.Time) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -29,30 +33,15 @@ func (t *Timer) Start() {
 	}
 
 	t.running = true
-	t.stopChan = make(chan struct{})
-
-	go func() {
-		ticker := time.NewTicker(t.interval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-t.stopChan:
-				return
-			case <-ticker.C:
-				t.mu.Lock()
-				if !t.running {
-					t.mu.Unlock()
-					return
-				}
-				t.elapsed += t.interval
-				t.mu.Unlock()
-			}
-		}
-	}()
+	t.startedAt = at
 }
 
 func (t *Timer) Stop() {
+	t.StopAt(time.Now())
+}
+
+// StopAt stops the timer, crediting the current run up to the given instant.
+func (t *Timer) StopAt(at time.Time) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -60,9 +49,9 @@ func (t *Timer) Stop() {
 		return
 	}
 
+	t.base += since(t.startedAt, at)
 	t.running = false
-	close(t.stopChan)
-	t.stopChan = make(chan struct{})
+	t.startedAt = time.Time{}
 }
 
 func (t *Timer) Reset() {
@@ -70,23 +59,44 @@ func (t *Timer) Reset() {
 	defer t.mu.Unlock()
 
 	t.running = false
-	t.elapsed = 0
-	if t.stopChan != nil {
-		close(t.stopChan)
-	}
-	t.stopChan = make(chan struct{})
+	t.base = 0
+	t.startedAt = time.Time{}
 }
 
+// SetElapsed sets the time banked before the current run.
 func (t *Timer) SetElapsed(d time.Duration) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.elapsed = d
+	t.base = d
 }
 
 func (t *Timer) Elapsed() time.Duration {
+	return t.ElapsedAt(time.Now())
+}
+
+// ElapsedAt returns the total elapsed time as of the given instant.
+func (t *Timer) ElapsedAt(now time.Time) time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.running {
+		return t.base
+	}
+	return t.base + since(t.startedAt, now)
+}
+
+// Base returns the time banked before the current run.
+func (t *Timer) Base() time.Duration {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	return t.elapsed
+	return t.base
+}
+
+// StartedAt returns the instant the current run started, or the zero time
+// when the timer is stopped.
+func (t *Timer) StartedAt() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.startedAt
 }
 
 func (t *Timer) Running() bool {
@@ -94,3 +104,19 @@ func (t *Timer) Running() bool {
 	defer t.mu.RUnlock()
 	return t.running
 }
+
+// since returns the time between start and now. When both instants carry a
+// monotonic reading it is immune to wall-clock steps, but on some platforms
+// the monotonic clock pauses while the machine is suspended, so the wall-clock
+// difference wins whenever it is larger. Instants loaded from the database
+// have no monotonic reading and fall back to the wall clock alone.
+func since(start, now time.Time) time.Duration {
+	d := now.Sub(start)
+	if wall := now.Round(0).Sub(start.Round(0)); wall > d {
+		d = wall
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,55 @@
+package timer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestElapsedFollowsStartInstants(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	tm := New()
+	tm.SetElapsed(10 * time.Minute)
+	tm.StartAt(start)
+
+	if got := tm.ElapsedAt(start.Add(90 * time.Second)); got != 11*time.Minute+30*time.Second {
+		t.Errorf("elapsed while running = %s, want 11m30s", got)
+	}
+
+	tm.StopAt(start.Add(5 * time.Minute))
+	if tm.Running() || !tm.StartedAt().IsZero() {
+		t.Fatal("timer still running after StopAt")
+	}
+	if got := tm.ElapsedAt(start.Add(time.Hour)); got != 15*time.Minute {
+		t.Errorf("elapsed after stop = %s, want 15m0s", got)
+	}
+	if got := tm.Base(); got != 15*time.Minute {
+		t.Errorf("base after stop = %s, want 15m0s", got)
+	}
+}
+
+// A run resumed from a stored instant counts the time since then, as after
+// a restart or a suspend.
+func TestElapsedCountsTimeBeforeResume(t *testing.T) {
+	tm := New()
+	tm.StartAt(time.Now().Add(-2 * time.Hour).Round(0))
+	if got := tm.Elapsed(); got < 2*time.Hour || got > 2*time.Hour+time.Minute {
+		t.Errorf("elapsed = %s, want about 2h", got)
+	}
+}
+
+func TestSinceNeverNegative(t *testing.T) {
+	now := time.Now()
+	if got := since(now, now.Add(-time.Second)); got != 0 {
+		t.Errorf("since a later start = %s, want 0", got)
+	}
+}
+
+func TestResetClearsEverything(t *testing.T) {
+	tm := New()
+	tm.SetElapsed(time.Hour)
+	tm.Start()
+	tm.Reset()
+	if tm.Running() || tm.Elapsed() != 0 {
+		t.Errorf("after reset: running = %v, elapsed %s", tm.Running(), tm.Elapsed())
+	}
+}
@@ -0,0 +1,187 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"timer_tui/internal/project"
+	"timer_tui/internal/timelog"
+)
+
+var (
+	// ErrLogTimes is returned for a log that does not stop after it starts.
+	ErrLogTimes = errors.New("a log must stop after it starts")
+	// ErrLogFuture is returned for a log that stops after now.
+	ErrLogFuture = errors.New("a log cannot stop in the future")
+)
+
+// OverlapError is returned for a log that shares time with logs already
+// recorded or with a running session. Only one timer runs at a time, so
+// logs of any project count.
+type OverlapError struct {
+	Logs []project.LogWithProject
+	// Running is the project whose running session overlaps, if any.
+	Running string
+}
+
+func (e *OverlapError) Error() string {
+	if len(e.Logs) == 0 {
+		return fmt.Sprintf("overlaps the running session of %s", e.Running)
+	}
+	l := e.Logs[0]
+	msg := fmt.Sprintf("overlaps %s logged %s-%s", l.ProjectName,
+		l.Log.StartedAt.Local().Format("Jan 02 15:04"), l.Log.StoppedAt.Local().Format("15:04"))
+	if n := len(e.Logs) - 1; n > 0 {
+		msg += fmt.Sprintf(" and %d more", n)
+	}
+	return msg
+}
+
+// AddLog records a session that was not timed, such as work done while the
+// app was not running, and credits it to the project's elapsed time. Its
+// overtime is worked out as if it were the project's latest log.
+func AddLog(tx project.Store, log timelog.TimeLog, now time.Time) (*project.Project, timelog.TimeLog, error) {
+	if err := checkLog(tx, log, now); err != nil {
+		return nil, log, err
+	}
+	log.ID = 0
+	log.Duration = log.StoppedAt.Sub(log.StartedAt)
+	log.Phase = ""
+	p, err := credit(tx, log.ProjectID, log.Duration)
+	if err != nil {
+		return nil, log, err
+	}
+	log.Overtime = p.OverBudget(p.Elapsed-log.Duration, p.Elapsed)
+	if err := tx.CreateLog(&log); err != nil {
+		return nil, log, err
+	}
+	return p, log, nil
+}
+
+// checkLog validates the times of a log to be saved.
+func checkLog(tx project.Store, log timelog.TimeLog, now time.Time) error {
+	if !log.StoppedAt.After(log.StartedAt) {
+		return ErrLogTimes
+	}
+	if log.StoppedAt.After(now) {
+		return ErrLogFuture
+	}
+	overlaps, err := tx.OverlappingLogs(log.StartedAt, log.StoppedAt, log.ID)
+	if err != nil {
+		return err
+	}
+	if len(overlaps) > 0 {
+		return &OverlapError{Logs: overlaps}
+	}
+	sessions, err := tx.GetActiveSessions()
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		if s.StartedAt.Before(log.StoppedAt) {
+			p, err := tx.GetByID(s.ProjectID)
+			if err != nil {
+				return err
+			}
+			return &OverlapError{Running: p.Name}
+		}
+	}
+	return nil
+}
+
+// Edited is a log as saved after an edit, with the projects whose elapsed
+// time changed: the one it belonged to, then the one it moved to, if any.
+type Edited struct {
+	Log      timelog.TimeLog
+	Projects []*project.Project
+}
+
+// EditLog saves changes to a log's start, stop, tag and project. Its
+// duration is recomputed and the difference credited to the elapsed time of
+// the projects involved. Its overtime is worked out as if it were the
+// project's latest log.
+func EditLog(tx project.Store, edited timelog.TimeLog, now time.Time) (Edited, error) {
+	old, err := tx.GetLog(edited.ID)
+	if err != nil {
+		return Edited{}, err
+	}
+	if err := checkLog(tx, edited, now); err != nil {
+		return Edited{}, err
+	}
+	edited.Duration = edited.StoppedAt.Sub(edited.StartedAt)
+	edited.Phase = old.Phase
+
+	var projects []*project.Project
+	if edited.ProjectID == old.ProjectID {
+		p, err := credit(tx, old.ProjectID, edited.Duration-old.Duration)
+		if err != nil {
+			return Edited{}, err
+		}
+		projects = append(projects, p)
+	} else {
+		from, err := credit(tx, old.ProjectID, -old.Duration)
+		if err != nil {
+			return Edited{}, err
+		}
+		to, err := credit(tx, edited.ProjectID, edited.Duration)
+		if err != nil {
+			return Edited{}, err
+		}
+		projects = append(projects, from, to)
+		if to.Pomodoro == nil {
+			edited.Phase = ""
+		}
+	}
+
+	p := projects[len(projects)-1]
+	edited.Overtime = p.OverBudget(p.Elapsed-edited.Duration, p.Elapsed)
+	if err := tx.UpdateLog(&edited); err != nil {
+		return Edited{}, err
+	}
+	return Edited{Log: edited, Projects: projects}, nil
+}
+
+// DeleteLog removes a log and takes its duration off the project's elapsed
+// time.
+func DeleteLog(tx project.Store, id int64) (*project.Project, error) {
+	log, err := tx.GetLog(id)
+	if err != nil {
+		return nil, err
+	}
+	p, err := credit(tx, log.ProjectID, -log.Duration)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.DeleteLog(id); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Note sets the note of the session a stop logged, which is its last log.
+// Nothing is logged for a stop during a pomodoro break, so the note then
+// goes to the work phase before it, if that was logged by the stop.
+func Note(tx project.Store, stopped *Stopped, note string) error {
+	n := len(stopped.Logs)
+	if note == "" || n == 0 {
+		return nil
+	}
+	log := &stopped.Logs[n-1]
+	log.Note = note
+	return tx.UpdateLog(log)
+}
+
+// credit adds d, which may be negative, to the project's elapsed time. It
+// never goes below zero, as the time may have been reset since.
+func credit(tx project.Store, id int64, d time.Duration) (*project.Project, error) {
+	p, err := tx.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	p.Elapsed = max(p.Elapsed+d, 0)
+	if err := tx.Update(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
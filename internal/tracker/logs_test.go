@@ -0,0 +1,115 @@
+package tracker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"timer_tui/internal/project"
+	"timer_tui/internal/timelog"
+)
+
+// Editing a log credits the change in its duration to the projects
+// involved, and deleting it takes its time back off.
+func TestEditAndDeleteLog(t *testing.T) {
+	store := project.NewMemoryStore()
+	write, err := store.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	review, err := store.Create("Review", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	if _, _, err := Start(store, write.ID, start, ""); err != nil {
+		t.Fatal(err)
+	}
+	stopped, err := Stop(store, write.ID, start.Add(30*time.Minute), "draft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := stopped.Logs[0]
+	now := start.Add(24 * time.Hour)
+
+	log.StoppedAt = start.Add(80 * time.Minute)
+	edited, err := EditLog(store, log, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Log.Duration != 80*time.Minute || edited.Log.Overtime != 20*time.Minute {
+		t.Errorf("edited log = %+v, want 80m with 20m overtime", edited.Log)
+	}
+	if len(edited.Projects) != 1 || edited.Projects[0].Elapsed != 80*time.Minute {
+		t.Fatalf("projects = %+v, want Write at 80m", edited.Projects)
+	}
+
+	log = edited.Log
+	log.ProjectID = review.ID
+	log.StoppedAt = start.Add(40 * time.Minute)
+	if edited, err = EditLog(store, log, now); err != nil {
+		t.Fatal(err)
+	}
+	if len(edited.Projects) != 2 || edited.Projects[0].Elapsed != 0 || edited.Projects[1].Elapsed != 40*time.Minute {
+		t.Fatalf("after moving: projects = %+v, want Write at 0 and Review at 40m", edited.Projects)
+	}
+
+	log = edited.Log
+	log.StoppedAt = log.StartedAt
+	if _, err := EditLog(store, log, now); !errors.Is(err, ErrLogTimes) {
+		t.Errorf("zero-length edit: %v, want ErrLogTimes", err)
+	}
+
+	p, err := DeleteLog(store, log.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != review.ID || p.Elapsed != 0 {
+		t.Errorf("after delete: %+v, want Review at 0", p)
+	}
+	if logs, err := store.GetLogsByProject(review.ID); err != nil || len(logs) != 0 {
+		t.Errorf("logs = %+v, %v; want none", logs, err)
+	}
+}
+
+// A session added by hand must end by now and not overlap any logged or
+// running time.
+func TestAddLogRejectsOverlaps(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	now := start.Add(3 * time.Hour)
+	at := func(from, to time.Duration) timelog.TimeLog {
+		return timelog.TimeLog{ProjectID: p.ID, StartedAt: start.Add(from), StoppedAt: start.Add(to), Tags: []string{"call"}}
+	}
+
+	added, log, err := AddLog(store, at(0, time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.Elapsed != time.Hour || log.ID == 0 || log.Duration != time.Hour || timelog.FormatTags(log.Tags) != "call" {
+		t.Fatalf("added %+v to %+v, want an hour logged", log, added)
+	}
+
+	var overlap *OverlapError
+	if _, _, err := AddLog(store, at(30*time.Minute, 90*time.Minute), now); !errors.As(err, &overlap) || len(overlap.Logs) != 1 {
+		t.Errorf("overlapping log: %v, want an OverlapError", err)
+	}
+	if _, _, err := AddLog(store, at(2*time.Hour, 4*time.Hour), now); !errors.Is(err, ErrLogFuture) {
+		t.Errorf("log into the future: %v, want ErrLogFuture", err)
+	}
+
+	if _, _, err := Start(store, p.ID, start.Add(150*time.Minute), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := AddLog(store, at(2*time.Hour, 160*time.Minute), now); !errors.As(err, &overlap) || overlap.Running != "Write" {
+		t.Errorf("log over the running session: %v, want an OverlapError", err)
+	}
+	if _, _, err := AddLog(store, at(time.Hour, 2*time.Hour), now); err != nil {
+		t.Errorf("adjacent log: %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+// Package tracker holds the rules for starting and stopping project timers,
+// shared by the TUI and the headless subcommands. Every function works on
+// the stored state of a project and is meant to run inside Store.WithTx.
+package tracker
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"timer_tui/internal/pomodoro"
+	"timer_tui/internal/project"
+	"timer_tui/internal/timelog"
+)
+
+var (
+	ErrAlreadyRunning = errors.New("timer is already running")
+	ErrNotRunning     = errors.New("timer is not running")
+)
+
+// Stopped is a project as stored after its timer stopped, along with the
+// logs written for it: completed pomodoro work phases, then the session that
+// was cut short. Stopping during a pomodoro break logs nothing further.
+type Stopped struct {
+	Project *project.Project
+	Logs    []timelog.TimeLog
+}
+
+// Start starts the project's timer at now. Only one timer runs at a time, so
+// any other running project is stopped first and its session logged without
+// a tag. tag is kept with the session and used if it is stopped untagged.
+func Start(tx project.Store, id int64, now time.Time, tag string) (*project.Project, []Stopped, error) {
+	tag = normalizeTag(tag)
+	p, err := tx.GetByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.Running {
+		return nil, nil, ErrAlreadyRunning
+	}
+
+	stopped, err := StopAll(tx, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.Running = true
+	p.StartedAt = now
+	if p.Pomodoro != nil {
+		p.Phase = pomodoro.Work
+		p.PhaseStartedAt = now
+		p.PomodoroTag = tag
+	}
+	if err := tx.Update(p); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.StartSession(p.ID, now, tag); err != nil {
+		return nil, nil, err
+	}
+	return p, stopped, nil
+}
+
+// Stop stops the project's timer at now, credits the session to the project
+// and logs it. An empty tag falls back to the one given when it started.
+func Stop(tx project.Store, id int64, now time.Time, tag string) (Stopped, error) {
+	p, err := tx.GetByID(id)
+	if err != nil {
+		return Stopped{}, err
+	}
+	if !p.Running {
+		return Stopped{}, ErrNotRunning
+	}
+
+	logs, err := advance(tx, p, now)
+	if err != nil {
+		return Stopped{}, err
+	}
+
+	if !p.StartedAt.IsZero() {
+		if tag == "" {
+			if tag, err = sessionTag(tx, p.ID); err != nil {
+				return Stopped{}, err
+			}
+		}
+		log, err := logSession(tx, p, now, tag)
+		if err != nil {
+			return Stopped{}, err
+		}
+		logs = append(logs, log)
+	}
+
+	p.Elapsed = p.ElapsedAt(now)
+	p.Running = false
+	p.StartedAt = time.Time{}
+	p.Phase = ""
+	p.PhaseStartedAt = time.Time{}
+	p.PomodoroTag = ""
+	if err := tx.Update(p); err != nil {
+		return Stopped{}, err
+	}
+	if err := tx.EndSession(p.ID); err != nil {
+		return Stopped{}, err
+	}
+	return Stopped{Project: p, Logs: logs}, nil
+}
+
+// Tag sets the tag the project's running session is logged with when it is
+// stopped untagged. For a pomodoro project it also applies to the work
+// phases still to come.
+func Tag(tx project.Store, id int64, tag string) (*project.Project, error) {
+	tag = normalizeTag(tag)
+	p, err := tx.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Running {
+		return nil, ErrNotRunning
+	}
+	if p.Pomodoro != nil {
+		p.PomodoroTag = tag
+		if err := tx.Update(p); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.SetSessionTag(p.ID, tag); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Split cuts from..to out of the project's running session into a log of
+// its own with the given tag, such as time the user was away. The session
+// is logged up to from and carries on from to with its original tag. The
+// returned logs are the session's, then the cut out one.
+func Split(tx project.Store, id int64, from, to time.Time, tag string) (Stopped, error) {
+	p, err := tx.GetByID(id)
+	if err != nil {
+		return Stopped{}, err
+	}
+	if !p.Running || p.StartedAt.IsZero() {
+		return Stopped{}, ErrNotRunning
+	}
+	if p.Pomodoro != nil {
+		return Stopped{}, errors.New("time cannot be split out of a pomodoro")
+	}
+	if from.Before(p.StartedAt) {
+		from = p.StartedAt
+	}
+	if to.Before(from) {
+		to = from
+	}
+
+	sessTag, err := sessionTag(tx, p.ID)
+	if err != nil {
+		return Stopped{}, err
+	}
+	session, err := logSession(tx, p, from, sessTag)
+	if err != nil {
+		return Stopped{}, err
+	}
+	p.Elapsed = p.ElapsedAt(from)
+	p.StartedAt = from
+	cut, err := logSession(tx, p, to, tag)
+	if err != nil {
+		return Stopped{}, err
+	}
+	p.Elapsed = p.ElapsedAt(to)
+	p.StartedAt = to
+
+	if err := tx.Update(p); err != nil {
+		return Stopped{}, err
+	}
+	if err := tx.StartSession(p.ID, to, sessTag); err != nil {
+		return Stopped{}, err
+	}
+	return Stopped{Project: p, Logs: []timelog.TimeLog{session, cut}}, nil
+}
+
+// AutoStopped is a project stopped when its budget ran out, with the
+// follow-up project started in its place, if any.
+type AutoStopped struct {
+	Stopped
+	// At is when the budget ran out, which the stop is dated to.
+	At   time.Time
+	Next *project.Project
+}
+
+// AutoStop stops the project's timer if it is set to stop at its budget and
+// the running session used the budget up by now. The stop is dated to the
+// moment the budget ran out and logged with the session's tag, or the
+// auto-stop tag if it has none. The follow-up project starts from that
+// moment. A nil result means the timer was left running.
+func AutoStop(tx project.Store, id int64, now time.Time) (*AutoStopped, error) {
+	p, err := tx.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Running || p.AutoStop == nil || p.OpenEnded() {
+		return nil, nil
+	}
+	if _, err := advance(tx, p, now); err != nil {
+		return nil, err
+	}
+	// Only a session that crosses the budget stops: one started in overtime
+	// was started on purpose.
+	if p.StartedAt.IsZero() || p.Elapsed >= p.MaxTime || p.ElapsedAt(now) < p.MaxTime {
+		return nil, nil
+	}
+	at := p.StartedAt.Add(p.MaxTime - p.Elapsed)
+
+	tag, err := sessionTag(tx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tag == "" {
+		tag = p.AutoStop.Tag
+	}
+	stopped, err := Stop(tx, p.ID, at, tag)
+	if err != nil {
+		return nil, err
+	}
+	result := &AutoStopped{Stopped: stopped, At: at}
+
+	if next := p.AutoStop.FollowUpID; next != 0 && next != p.ID {
+		result.Next, _, err = Start(tx, next, at, "")
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("start follow-up: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// Advance moves a running pomodoro project through every phase that ended
+// before now, logging each completed work phase. Other projects are returned
+// as stored.
+func Advance(tx project.Store, id int64, now time.Time) (*project.Project, []timelog.TimeLog, error) {
+	p, err := tx.GetByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	logs, err := advance(tx, p, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, logs, nil
+}
+
+func advance(tx project.Store, p *project.Project, now time.Time) ([]timelog.TimeLog, error) {
+	if p.Pomodoro == nil || !p.Running {
+		return nil, nil
+	}
+	if p.PhaseStartedAt.IsZero() {
+		// Pomodoro mode was switched on while the timer was running.
+		p.Phase = pomodoro.Work
+		p.PhaseStartedAt = p.StartedAt
+		if p.PhaseStartedAt.IsZero() {
+			p.PhaseStartedAt = now
+		}
+	}
+
+	end := p.PhaseEnd()
+	if !end.Before(now) {
+		return nil, nil
+	}
+
+	tag := p.PomodoroTag
+	var logs []timelog.TimeLog
+	for ; end.Before(now); end = p.PhaseEnd() {
+		if p.Phase == pomodoro.Work {
+			log, err := logSession(tx, p, end, tag)
+			if err != nil {
+				return nil, err
+			}
+			logs = append(logs, log)
+			p.Elapsed = p.ElapsedAt(end)
+			p.StartedAt = time.Time{}
+			p.Cycle++
+			if err := tx.EndSession(p.ID); err != nil {
+				return nil, err
+			}
+		} else {
+			p.StartedAt = end
+			if err := tx.StartSession(p.ID, end, tag); err != nil {
+				return nil, err
+			}
+		}
+		p.Phase = p.Pomodoro.Next(p.Phase, p.Cycle)
+		p.PhaseStartedAt = end
+	}
+
+	if err := tx.Update(p); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// logSession writes a log for the project's running session up to end.
+func logSession(tx project.Store, p *project.Project, end time.Time, tag string) (timelog.TimeLog, error) {
+	startedAt := p.StartedAt
+	if startedAt.IsZero() || end.Before(startedAt) {
+		startedAt = end
+	}
+	log := timelog.TimeLog{
+		ProjectID: p.ID,
+		StartedAt: startedAt,
+		StoppedAt: end,
+		Duration:  end.Sub(startedAt),
+		Tags:      timelog.ParseTags(tag),
+	}
+	log.Overtime = p.OverBudget(p.Elapsed, p.Elapsed+log.Duration)
+	if p.Pomodoro != nil {
+		log.Phase = string(p.Phase)
+	}
+	if err := tx.CreateLog(&log); err != nil {
+		return log, err
+	}
+	return log, nil
+}
+
+// normalizeTag writes the tags in tag the way they are shown, so "#a #b"
+// given when starting reads "a, b" in the status.
+func normalizeTag(tag string) string {
+	return timelog.FormatTags(timelog.ParseTags(tag))
+}
+
+// sessionTag returns the tag given when the project's session started.
+func sessionTag(tx project.Store, id int64) (string, error) {
+	sessions, err := tx.GetActiveSessions()
+	if err != nil {
+		return "", err
+	}
+	for _, s := range sessions {
+		if s.ProjectID == id {
+			return s.Tag, nil
+		}
+	}
+	return "", nil
+}
+
+// StopAll stops every running timer at now, logging each session untagged
+// unless a tag was given when it started.
+func StopAll(tx project.Store, now time.Time) ([]Stopped, error) {
+	projects, err := tx.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var stopped []Stopped
+	for _, p := range projects {
+		if !p.Running {
+			continue
+		}
+		s, err := Stop(tx, p.ID, now, "")
+		if err != nil {
+			return nil, fmt.Errorf("stop %s: %w", p.Name, err)
+		}
+		stopped = append(stopped, s)
+	}
+	return stopped, nil
+}
+
+// Reset clears the project's elapsed time and pomodoro progress, and discards
+// any running session without logging it.
+func Reset(tx project.Store, id int64) (*project.Project, error) {
+	p, err := tx.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	p.Elapsed = 0
+	p.Running = false
+	p.StartedAt = time.Time{}
+	p.Phase = ""
+	p.PhaseStartedAt = time.Time{}
+	p.Cycle = 0
+	p.PomodoroTag = ""
+	if err := tx.Update(p); err != nil {
+		return nil, err
+	}
+	if err := tx.EndSession(p.ID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,194 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"timer_tui/internal/pomodoro"
+	"timer_tui/internal/project"
+	"timer_tui/internal/timelog"
+)
+
+// Stopping a pomodoro logs each finished work phase and the cut-short one,
+// and credits only work time.
+func TestStopLogsPomodoroWorkPhases(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Focus", 4*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := pomodoro.DefaultSettings()
+	p.Pomodoro = &settings
+	if err := store.Update(p); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	if _, _, err := Start(store, p.ID, start, "deep"); err != nil {
+		t.Fatal(err)
+	}
+	stopped, err := Stop(store, p.ID, start.Add(65*time.Minute), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 25m work, 5m break, 25m work, 5m break, then 5m of work.
+	want := []time.Duration{25 * time.Minute, 25 * time.Minute, 5 * time.Minute}
+	if len(stopped.Logs) != len(want) {
+		t.Fatalf("logs = %+v, want %d", stopped.Logs, len(want))
+	}
+	for i, l := range stopped.Logs {
+		if l.Duration != want[i] || l.Phase != string(pomodoro.Work) || timelog.FormatTags(l.Tags) != "deep" {
+			t.Errorf("log %d = %s %s %q, want %s of work tagged deep", i, l.Duration, l.Phase, l.Tags, want[i])
+		}
+	}
+	if got := stopped.Project.Elapsed; got != 55*time.Minute {
+		t.Errorf("elapsed = %s, want 55m0s", got)
+	}
+	if stopped.Project.Running || stopped.Project.Phase != "" {
+		t.Errorf("project still running in phase %q", stopped.Project.Phase)
+	}
+}
+
+// Stopping during a break logs nothing further.
+func TestStopDuringBreak(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Focus", 4*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := pomodoro.DefaultSettings()
+	p.Pomodoro = &settings
+	if err := store.Update(p); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	if _, _, err := Start(store, p.ID, start, ""); err != nil {
+		t.Fatal(err)
+	}
+	stopped, err := Stop(store, p.ID, start.Add(27*time.Minute), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stopped.Logs) != 1 || stopped.Logs[0].Duration != 25*time.Minute {
+		t.Fatalf("logs = %+v, want only the finished work phase", stopped.Logs)
+	}
+	if got := stopped.Project.Elapsed; got != 25*time.Minute {
+		t.Errorf("elapsed = %s, want 25m0s", got)
+	}
+}
+
+// A session that runs past the budget logs the part beyond it as overtime.
+func TestStopRecordsOvertime(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Elapsed = 50 * time.Minute
+	if err := store.Update(p); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	if _, _, err := Start(store, p.ID, start, ""); err != nil {
+		t.Fatal(err)
+	}
+	stopped, err := Stop(store, p.ID, start.Add(20*time.Minute), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stopped.Logs) != 1 || stopped.Logs[0].Overtime != 10*time.Minute {
+		t.Fatalf("logs = %+v, want one with 10m overtime", stopped.Logs)
+	}
+	if got := stopped.Project.Overtime(); got != 10*time.Minute {
+		t.Errorf("project overtime = %s, want 10m0s", got)
+	}
+}
+
+// Splitting out time away logs the session up to then and the gap on its
+// own, and the session carries on with its tag.
+func TestSplitCutsOutIdleTime(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", 4*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	if _, _, err := Start(store, p.ID, start, "draft"); err != nil {
+		t.Fatal(err)
+	}
+
+	away, back := start.Add(40*time.Minute), start.Add(70*time.Minute)
+	split, err := Split(store, p.ID, away, back, "away")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(split.Logs) != 2 ||
+		split.Logs[0].Duration != 40*time.Minute || timelog.FormatTags(split.Logs[0].Tags) != "draft" ||
+		!split.Logs[1].StartedAt.Equal(away) || !split.Logs[1].StoppedAt.Equal(back) || timelog.FormatTags(split.Logs[1].Tags) != "away" {
+		t.Fatalf("logs = %+v, want 40m tagged draft then the gap tagged away", split.Logs)
+	}
+	if !split.Project.Running || !split.Project.StartedAt.Equal(back) || split.Project.Elapsed != 70*time.Minute {
+		t.Fatalf("project = %+v, want running since %s with 70m", split.Project, back)
+	}
+
+	stopped, err := Stop(store, p.ID, back.Add(20*time.Minute), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(stopped.Logs); n != 1 || timelog.FormatTags(stopped.Logs[0].Tags) != "draft" || stopped.Logs[0].Duration != 20*time.Minute {
+		t.Fatalf("stop logged %+v, want 20m tagged draft", stopped.Logs)
+	}
+}
+
+// A session that runs past the budget is stopped when it ran out, tagged
+// for the auto-stop, and the follow-up starts from then. One started in
+// overtime is left alone.
+func TestAutoStopStartsFollowUp(t *testing.T) {
+	store := project.NewMemoryStore()
+	p, err := store.Create("Write", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := store.Create("Review", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Elapsed = 30 * time.Minute
+	p.AutoStop = &project.AutoStop{Tag: "budget", FollowUpID: next.ID}
+	if err := store.Update(p); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	if _, _, err := Start(store, p.ID, start, ""); err != nil {
+		t.Fatal(err)
+	}
+	if auto, err := AutoStop(store, p.ID, start.Add(29*time.Minute)); err != nil || auto != nil {
+		t.Fatalf("before the budget ran out: %+v, %v; want nothing", auto, err)
+	}
+
+	ranOut := start.Add(30 * time.Minute)
+	auto, err := AutoStop(store, p.ID, start.Add(45*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auto == nil || !auto.At.Equal(ranOut) || auto.Project.Running || auto.Project.Elapsed != time.Hour {
+		t.Fatalf("auto-stop = %+v, want Write stopped at %s with 1h", auto, ranOut)
+	}
+	if n := len(auto.Logs); n != 1 || timelog.FormatTags(auto.Logs[0].Tags) != "budget" || !auto.Logs[0].StoppedAt.Equal(ranOut) {
+		t.Fatalf("logs = %+v, want one tagged budget", auto.Logs)
+	}
+	if auto.Next == nil || auto.Next.ID != next.ID || !auto.Next.StartedAt.Equal(ranOut) {
+		t.Fatalf("follow-up = %+v, want Review started at %s", auto.Next, ranOut)
+	}
+
+	if _, _, err := Start(store, p.ID, start.Add(time.Hour), ""); err != nil {
+		t.Fatal(err)
+	}
+	if auto, err := AutoStop(store, p.ID, start.Add(2*time.Hour)); err != nil || auto != nil {
+		t.Fatalf("session started in overtime: %+v, %v; want it left running", auto, err)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"timer_tui/internal/pomodoro"
 	"timer_tui/internal/project"
 	"timer_tui/internal/timelog"
 
@@ -82,6 +84,11 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%02d:%02d", minutes, seconds)
 }
 
+// formatOvertime formats time past a project's budget as +MM:SS.
+func formatOvertime(d time.Duration) string {
+	return "+" + formatDuration(d)
+}
+
 func (m *Model) emptyStateView() string {
 	return lipgloss.Place(
 		80, 24,
@@ -104,6 +111,10 @@ func (m *Model) mainView() string {
 	)
 	sb.WriteString(boxes)
 	sb.WriteString("\n\n")
+	if m.Err != nil {
+		sb.WriteString(errorStyle.Render("Error: " + m.Err.Error()))
+		sb.WriteString("\n")
+	}
 	sb.WriteString(helpStyle.Render("Navigate: Up/Down | Start/Stop: Enter | New: n | Edit: e | Delete: d | Reset: r | Logs: l | Quit: q"))
 
 	return sb.String()
@@ -119,10 +130,16 @@ func (m *Model) projectListView() string {
 		running := ""
 		if t.Running() {
 			running = " ●"
+		} else if p.Running {
+			// On a pomodoro break
+			running = " ○"
+		}
+		timerStr := formatDuration(p.Remaining())
+		if p.OpenEnded() {
+			timerStr = formatDuration(p.Elapsed)
+		} else if ot := p.Overtime(); ot > 0 {
+			timerStr = overtimeStyle.Render(formatOvertime(ot))
 		}
-		remaining := p.MaxTime - p.Elapsed
-		remaining = max(remaining, 0)
-		timerStr := formatDuration(remaining)
 
 		line := fmt.Sprintf("%s %s%s", p.Name, timerStr, running)
 
@@ -144,14 +161,19 @@ func (m *Model) projectDetailView() string {
 	}
 
 	t := m.Timers[p.ID]
-	remaining := p.MaxTime - p.Elapsed
-	remaining = max(remaining, 0)
+	// Open-ended projects count up; budgeted ones count down.
+	shown := p.Remaining()
+	if p.OpenEnded() {
+		shown = p.Elapsed
+	}
 
 	var timerStr string
-	if t.Running() {
-		timerStr = timerRunningStyle.Render(formatDuration(remaining))
+	if ot := p.Overtime(); ot > 0 {
+		timerStr = overtimeStyle.Render(formatOvertime(ot) + " overtime")
+	} else if t.Running() {
+		timerStr = timerRunningStyle.Render(formatDuration(shown))
 	} else {
-		timerStr = timerDisplayStyle.Render(formatDuration(remaining))
+		timerStr = timerDisplayStyle.Render(formatDuration(shown))
 	}
 
 	status := "Stopped"
@@ -159,15 +181,25 @@ func (m *Model) projectDetailView() string {
 	if t.Running() {
 		status = "Running"
 		statusStyle = runningStyle
+	} else if p.Running {
+		status = "On break"
+		statusStyle = runningStyle
 	}
 
 	maxTimeStr := fmt.Sprintf("Max: %s", formatDuration(p.MaxTime))
+	if p.OpenEnded() {
+		maxTimeStr = "Max: none (stopwatch)"
+	}
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("Project: %s\n\n", p.Name))
 	sb.WriteString(timerStr)
 	sb.WriteString(fmt.Sprintf("\n\n%s\n", statusStyle.Render(status)))
 	sb.WriteString(fmt.Sprintf("%s\n", maxTimeStr))
+	if p.Pomodoro != nil {
+		sb.WriteString(m.pomodoroView(p))
+		sb.WriteString("\n")
+	}
 
 	// Show recent time logs
 	logs := m.TimeLogs[p.ID]
@@ -188,172 +220,297 @@ func (m *Model) projectDetailView() string {
 	return boxStyle.Width(45).Height(15).Render(sb.String())
 }
 
+// pomodoroView describes a pomodoro project's current phase and cycle, or
+// its settings while stopped.
+func (m *Model) pomodoroView(p *project.Project) string {
+	if !p.Running || p.Phase == "" {
+		return fmt.Sprintf("Pomodoro: %s", p.Pomodoro)
+	}
+	left := max(time.Until(p.PhaseEnd()), 0)
+	return fmt.Sprintf("%s %s · Cycle %d/%d",
+		runningStyle.Render(p.Phase.String()),
+		formatDuration(left),
+		p.PomodoroCycle(), p.Pomodoro.Cycles,
+	)
+}
+
 func (m *Model) addFormView() string {
-	var sb strings.Builder
-	sb.WriteString(titleStyle.Width(80).Render("Add New Project"))
-	sb.WriteString("\n\n")
+	return m.projectFormView("Add New Project")
+}
 
-	// Add a visible focus marker so it's obvious which field is active.
-	nameMarker := "  "
-	if m.InputFocus == 0 {
-		nameMarker = "→ "
-	}
-	nameLabel := fmt.Sprintf("%sProject Name: ", nameMarker)
-	if m.InputFocus == 0 {
-		nameLabel = inputStyle.Render(nameLabel)
-	} else {
-		nameLabel = inputInactiveStyle.Render(nameLabel)
-	}
+func (m *Model) editFormView() string {
+	return m.projectFormView("Edit Project")
+}
 
-	timeMarker := "  "
-	if m.InputFocus == 1 {
-		timeMarker = "→ "
-	}
-	timeLabel := fmt.Sprintf("%sDuration (min): ", timeMarker)
-	if m.InputFocus == 1 {
-		timeLabel = inputStyle.Render(timeLabel)
-	} else {
-		timeLabel = inputInactiveStyle.Render(timeLabel)
+func (m *Model) projectFormView(title string) string {
+	fields := []struct{ label, value string }{
+		{"Project Name", m.NewProjectName},
+		{"Duration (min, blank for none)", m.NewProjectTime},
+		{"Pomodoro (work/short/long x cycles)", m.NewProjectPomodoro},
+		{"Notify (bell,osc9,osc777@minutes left)", m.NewProjectNotify},
+		{"Notify command", m.NewProjectNotifyCommand},
+		{"Auto-stop (on [tag] [> next project])", m.NewProjectAutoStop},
 	}
 
-	nameValue := m.NewProjectName
-	if m.InputFocus == 0 {
-		nameValue = inputStyle.Render(nameValue + "\u2588")
+	var form strings.Builder
+	form.WriteString(titleStyle.Render(title))
+	form.WriteString("\n\n")
+	for i, f := range fields {
+		// Add a visible focus marker so it's obvious which field is active.
+		label := "  " + f.label + ": "
+		value := f.value
+		if i == m.InputFocus {
+			label = inputStyle.Render("→ " + f.label + ": ")
+			value = inputStyle.Render(value + "\u2588")
+		} else {
+			label = inputInactiveStyle.Render(label)
+		}
+		form.WriteString(label + value + "\n\n")
 	}
 
-	timeValue := m.NewProjectTime
-	if m.InputFocus == 1 {
-		timeValue = inputStyle.Render(timeValue + "\u2588")
+	form.WriteString(inactiveStyle.Render("Pomodoro: blank for a plain timer, \"on\" for 25/5/15x4"))
+	form.WriteString("\n")
+	form.WriteString(inactiveStyle.Render("Notify: e.g. bell,osc777@5 warns 5 min before the end"))
+	form.WriteString("\n")
+	form.WriteString(inactiveStyle.Render("Auto-stop: e.g. on budget > Review stops at the end"))
+	form.WriteString("\n\n")
+	if m.Err != nil {
+		form.WriteString(errorStyle.Render(m.Err.Error()))
+		form.WriteString("\n\n")
 	}
 
 	// Show which field is currently focused in the help line to make tab behavior explicit
-	focusName := "Project Name"
-	if m.InputFocus == 1 {
-		focusName = "Duration"
-	}
-	helpText := fmt.Sprintf("Tab: Switch (Focused: %s) | Enter: Save | Esc: Cancel", focusName)
-
-	form := fmt.Sprintf("%s%s\n\n%s%s\n\n%s",
-		nameLabel, nameValue,
-		timeLabel, timeValue,
-		helpStyle.Render(helpText),
-	)
+	helpText := fmt.Sprintf("Tab: Switch (Focused: %s) | Enter: Next/Save | Esc: Cancel", fields[m.InputFocus].label)
+	form.WriteString(helpStyle.Render(helpText))
 
 	return lipgloss.Place(
 		80, 24,
 		lipgloss.Center, lipgloss.Center,
-		boxStyle.Width(50).Render(form),
+		boxStyle.Width(60).Render(form.String()),
 	)
 }
 
-func (m *Model) editFormView() string {
+func (m *Model) tagInputView() string {
 	var sb strings.Builder
-	sb.WriteString(titleStyle.Width(80).Render("Edit Project"))
+	sb.WriteString(titleStyle.Width(80).Render("Log Time Session"))
 	sb.WriteString("\n\n")
 
-	// Add a visible focus marker so it's obvious which field is active.
-	nameMarker := "  "
-	if m.InputFocus == 0 {
-		nameMarker = "→ "
+	durationStr := ""
+	if m.PendingLog != nil {
+		durationStr = formatDuration(m.PendingLog.Duration)
 	}
-	nameLabel := fmt.Sprintf("%sProject Name: ", nameMarker)
-	if m.InputFocus == 0 {
-		nameLabel = inputStyle.Render(nameLabel)
-	} else {
-		nameLabel = inputInactiveStyle.Render(nameLabel)
+
+	tagLabel := inputStyle.Render("→ Tags: ")
+	tagValue := inputStyle.Render(m.TagInput+"\u2588") + tagSuggestionsView(m.TagCompletion)
+	noteLabel := inputInactiveStyle.Render("  Note: ")
+	noteValue := indentLines(m.NoteInput, 8)
+	if m.NoteFocus {
+		tagLabel = inputInactiveStyle.Render("  Tags: ")
+		tagValue = m.TagInput
+		noteLabel = inputStyle.Render("→ Note: ")
+		noteValue = inputStyle.Render(indentLines(m.NoteInput+"\u2588", 8))
 	}
 
-	timeMarker := "  "
-	if m.InputFocus == 1 {
-		timeMarker = "→ "
+	form := fmt.Sprintf(
+		"%s\n\n%s%s\n\n%s%s\n\n%s",
+		fmt.Sprintf("Session duration: %s", timerDisplayStyle.Render(durationStr)),
+		tagLabel, tagValue,
+		noteLabel, noteValue,
+		helpStyle.Render("Enter: Save | Tab: Complete tag/Note | Up/Down: Tags/Note\nAlt+Enter: New line | Esc: Skip"),
+	)
+
+	return lipgloss.Place(
+		80, 24,
+		lipgloss.Center, lipgloss.Center,
+		boxStyle.Width(64).Render(form),
+	)
+}
+
+// logQueryView is the log viewer's filter bar: the inputs while it is open,
+// otherwise the filter in use, if any.
+func (m *Model) logQueryView() string {
+	q := m.LogQueryEdit
+	if q == nil {
+		if !m.LogQuery.Active() {
+			return ""
+		}
+		q = &m.LogQuery
 	}
-	timeLabel := fmt.Sprintf("%sDuration (min): ", timeMarker)
-	if m.InputFocus == 1 {
-		timeLabel = inputStyle.Render(timeLabel)
-	} else {
-		timeLabel = inputInactiveStyle.Render(timeLabel)
+	fields := []struct{ label, value string }{
+		{"Project", q.Project},
+		{"Tags", q.Tags},
+		{"Range", q.Range},
+		{"Search", q.Search},
+	}
+	items := make([]string, len(fields))
+	for i, f := range fields {
+		if m.LogQueryEdit != nil && i == q.Focus {
+			items[i] = inputStyle.Render(f.label + ": " + f.value + "\u2588")
+		} else {
+			items[i] = inputInactiveStyle.Render(f.label+": ") + f.value
+		}
+	}
+	bar := "  " + strings.Join(items, "  ")
+	if m.LogQueryEdit == nil {
+		bar += "  " + helpStyle.Render("(x: clear)")
 	}
+	return bar
+}
 
-	nameValue := m.NewProjectName
-	if m.InputFocus == 0 {
-		nameValue = inputStyle.Render(nameValue + "\u2588")
+// logViewHelp returns the log viewer's key help, or the filter bar's while
+// it is open.
+func (m *Model) logViewHelp(help string) string {
+	if m.LogQueryEdit != nil {
+		return "Tab: Next field | ←/→: Range presets | Enter: Apply | Esc: Cancel"
 	}
+	return help
+}
 
-	timeValue := m.NewProjectTime
-	if m.InputFocus == 1 {
-		timeValue = inputStyle.Render(timeValue + "\u2588")
+// tagSuggestionsView lists the tag suggestions on a line below the tag
+// input, highlighting the one Tab chose.
+func tagSuggestionsView(c TagCompletion) string {
+	if len(c.Suggestions) == 0 {
+		return ""
 	}
+	items := make([]string, len(c.Suggestions))
+	for i, tag := range c.Suggestions {
+		if i == c.Choice {
+			items[i] = inputStyle.Render(tag)
+		} else {
+			items[i] = inactiveStyle.Render(tag)
+		}
+	}
+	return "\n" + strings.Repeat(" ", 8) + strings.Join(items, "  ")
+}
 
-	// Show which field is currently focused in the help line to make tab behavior explicit
-	focusName := "Project Name"
-	if m.InputFocus == 1 {
-		focusName = "Duration"
+// indentLines indents every line of s after the first by n spaces, to line
+// a multi-line value up after its label.
+func indentLines(s string, n int) string {
+	return strings.ReplaceAll(s, "\n", "\n"+strings.Repeat(" ", n))
+}
+
+func (m *Model) recoveryView() string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Width(80).Render("Recover Interrupted Session"))
+	sb.WriteString("\n\n")
+
+	s := m.Recoveries[0]
+	name := "(unknown project)"
+	if p := m.projectByID(s.ProjectID); p != nil {
+		name = p.Name
+	}
+	gap := time.Since(s.LastSeenAt)
+	if gap < 0 {
+		gap = 0
 	}
-	helpText := fmt.Sprintf("Tab: Switch (Focused: %s) | Enter: Save | Esc: Cancel", focusName)
 
-	form := fmt.Sprintf("%s%s\n\n%s%s\n\n%s",
-		nameLabel, nameValue,
-		timeLabel, timeValue,
-		helpStyle.Render(helpText),
+	info := fmt.Sprintf(
+		"Project: %s\nStarted: %s\nLast seen: %s\nGap: %s",
+		logProjectStyle.Render(name),
+		logTimeStyle.Render(s.StartedAt.Format("Jan 02 15:04")),
+		logTimeStyle.Render(s.LastSeenAt.Format("Jan 02 15:04")),
+		timerDisplayStyle.Render(formatDuration(gap)),
 	)
 
+	var prompt, help string
+	if m.RecoveryTrim {
+		prompt = inputStyle.Render("→ End time (HH:MM): ") + inputStyle.Render(m.RecoveryInput+"\u2588")
+		help = "Enter: Confirm | Esc: Back"
+	} else {
+		prompt = "The app stopped while this timer was running."
+		help = "f: Credit full gap | t: Trim | x: Discard gap | q: Quit"
+	}
+	if m.Err != nil {
+		prompt += "\n" + errorStyle.Render(m.Err.Error())
+	}
+
+	form := fmt.Sprintf("%s\n\n%s\n\n%s", info, prompt, helpStyle.Render(help))
+
 	return lipgloss.Place(
 		80, 24,
 		lipgloss.Center, lipgloss.Center,
-		boxStyle.Width(50).Render(form),
+		boxStyle.Width(60).Render(form),
 	)
 }
 
-func (m *Model) tagInputView() string {
-	var sb strings.Builder
-	sb.WriteString(titleStyle.Width(80).Render("Log Time Session"))
-	sb.WriteString("\n\n")
-
-	durationStr := ""
-	if m.PendingLog != nil {
-		durationStr = formatDuration(m.PendingLog.Duration)
+func (m *Model) idleView() string {
+	gap := m.IdleGap
+	name := "(unknown project)"
+	if p := m.projectByID(gap.ProjectID); p != nil {
+		name = p.Name
 	}
 
-	label := inputStyle.Render("→ Tag: ")
-	value := inputStyle.Render(m.TagInput + "\u2588")
-
-	form := fmt.Sprintf(
-		"%s\n\n%s%s\n\n%s",
-		fmt.Sprintf("Session duration: %s", timerDisplayStyle.Render(durationStr)),
-		label, value,
-		helpStyle.Render("Enter: Save | Esc: Skip (no tag)"),
+	info := fmt.Sprintf(
+		"Project: %s\nAway: %s – %s\nIdle: %s",
+		logProjectStyle.Render(name),
+		logTimeStyle.Render(gap.From.Format("15:04")),
+		logTimeStyle.Render(gap.To.Format("15:04")),
+		timerDisplayStyle.Render(formatDuration(gap.To.Sub(gap.From))),
 	)
 
+	var prompt, help string
+	if m.IdleSplit {
+		prompt = inputStyle.Render("→ Tag for the idle time: ") + inputStyle.Render(m.IdleTagInput+"\u2588")
+		help = "Enter: Log it separately | Esc: Back"
+	} else {
+		prompt = "The timer kept running while you were away."
+		help = "k: Keep | d: Discard, stop at " + gap.From.Format("15:04") + " | s: Split out"
+	}
+	if m.Err != nil {
+		prompt += "\n" + errorStyle.Render(m.Err.Error())
+	}
+
+	form := fmt.Sprintf("%s\n\n%s\n\n%s\n\n%s",
+		titleStyle.Render("Welcome Back"), info, prompt, helpStyle.Render(help))
+
 	return lipgloss.Place(
 		80, 24,
 		lipgloss.Center, lipgloss.Center,
-		boxStyle.Width(50).Render(form),
+		boxStyle.Width(60).Render(form),
 	)
 }
 
 func (m *Model) formatLogEntry(l timelog.TimeLog) string {
 	timeStr := logTimeStyle.Render(l.StoppedAt.Format("Jan 02 15:04"))
 	dur := formatDuration(l.Duration)
+	if l.Overtime > 0 {
+		dur += " " + overtimeStyle.Render(formatOvertime(l.Overtime))
+	}
+	if l.Phase != "" {
+		dur += " " + inactiveStyle.Render("("+strings.ToLower(pomodoro.Phase(l.Phase).String())+")")
+	}
 	tag := ""
-	if l.Tag != "" {
-		tag = " " + logTagStyle.Render("["+l.Tag+"]")
+	if len(l.Tags) > 0 {
+		tag = " " + logTagStyle.Render("["+timelog.FormatTags(l.Tags)+"]")
 	}
 	return fmt.Sprintf("  %s  %s%s", timeStr, dur, tag)
 }
 
 func (m *Model) allLogsView() string {
+	if m.LogEdit != nil {
+		return m.logEditView()
+	}
+
 	var sb strings.Builder
 
 	sb.WriteString(titleStyle.Width(80).Render("All Time Logs"))
-	sb.WriteString("\n\n")
+	sb.WriteString("\n")
+	sb.WriteString(m.logQueryView())
+	sb.WriteString("\n")
 
 	if len(m.AllLogs) == 0 {
-		content := boxStyle.Width(76).Height(18).Render(
-			inactiveStyle.Render("No time logs recorded yet."),
-		)
+		empty := "No time logs recorded yet."
+		if m.LogQuery.Active() {
+			empty = "No time logs match the filter."
+		}
+		content := boxStyle.Width(76).Height(18).Render(inactiveStyle.Render(empty))
 		sb.WriteString(content)
 		sb.WriteString("\n\n")
-		sb.WriteString(helpStyle.Render("Esc/l: Back | q: Quit"))
+		if m.Err != nil {
+			sb.WriteString(errorStyle.Render(m.Err.Error()))
+			sb.WriteString("\n")
+		}
+		sb.WriteString(helpStyle.Render(m.logViewHelp("a: Add | f: Filter | Esc/l: Back | q: Quit")))
 		return sb.String()
 	}
 
@@ -362,7 +519,7 @@ func (m *Model) allLogsView() string {
 		logTableHeaderStyle.Render("Project"),
 		logTableHeaderStyle.Render("Date"),
 		logTableHeaderStyle.Render("Duration"),
-		logTableHeaderStyle.Render("Tag"),
+		logTableHeaderStyle.Render("Tags"),
 	)
 
 	var tableBody strings.Builder
@@ -373,17 +530,14 @@ func (m *Model) allLogsView() string {
 	visibleRows := 15
 	totalLogs := len(m.AllLogs)
 
-	// Clamp scroll so we don't go past the end
-	maxScroll := totalLogs - visibleRows
-	if maxScroll < 0 {
-		maxScroll = 0
+	// Clamp the selection to the logs, then scroll so it is in view
+	if m.LogViewScroll > totalLogs-1 {
+		m.LogViewScroll = totalLogs - 1
 	}
-	if m.LogViewScroll > maxScroll {
-		m.LogViewScroll = maxScroll
+	start := 0
+	if m.LogViewScroll >= visibleRows {
+		start = m.LogViewScroll - visibleRows + 1
 	}
-
-	// Determine slice to display
-	start := m.LogViewScroll
 	end := start + visibleRows
 	if end > totalLogs {
 		end = totalLogs
@@ -396,37 +550,109 @@ func (m *Model) allLogsView() string {
 		tableBody.WriteString("\n")
 	}
 
-	// Scroll indicators
+	// Total of the logs shown, then scroll indicators
+	var total time.Duration
+	for _, lp := range m.AllLogs {
+		total += lp.Log.Duration
+	}
+	count := fmt.Sprintf("%d logs", totalLogs)
+	if totalLogs == 1 {
+		count = "1 log"
+	}
+	summary := fmt.Sprintf("Total %s in %s", formatDuration(total), count)
 	if totalLogs > visibleRows {
-		scrollInfo := fmt.Sprintf("  %s",
-			inactiveStyle.Render(fmt.Sprintf("(%d-%d of %d)", start+1, end, totalLogs)),
-		)
-		tableBody.WriteString(scrollInfo)
+		summary += fmt.Sprintf("  (%d-%d of %d)", start+1, end, totalLogs)
 	}
+	tableBody.WriteString("  " + inactiveStyle.Render(summary))
 
 	sb.WriteString(boxStyle.Width(76).Height(18).Render(tableBody.String()))
 	sb.WriteString("\n\n")
-	sb.WriteString(helpStyle.Render("Up/Down: Scroll | Esc/l: Back | q: Quit"))
+	if m.Err != nil {
+		sb.WriteString(errorStyle.Render(m.Err.Error()))
+		sb.WriteString("\n")
+	}
+	if m.ConfirmLogDelete {
+		sb.WriteString(errorStyle.Render("Delete the selected log? y: Delete | any other key: Keep"))
+	} else {
+		sb.WriteString(helpStyle.Render(m.logViewHelp("↑/↓: Select | a: Add | e: Edit | d: Delete | f: Filter | Esc/l: Back | q: Quit")))
+	}
 
 	return sb.String()
 }
 
+func (m *Model) logEditView() string {
+	f := m.LogEdit
+	fields := []struct{ label, value string }{
+		{"Start", f.Start},
+		{"Stop", f.Stop},
+		{"Tags", f.Tag},
+		{"Project", f.Project},
+		{"Note", f.Note},
+	}
+
+	var form strings.Builder
+	form.WriteString(titleStyle.Render(logFormTitle(f.Log.Log)))
+	form.WriteString("\n\n")
+	for i, field := range fields {
+		label := "  " + field.label + ": "
+		value := indentLines(field.value, len(label))
+		if i == f.Focus {
+			label = inputStyle.Render("→ " + field.label + ": ")
+			value = inputStyle.Render(indentLines(field.value+"\u2588", len(field.label)+4))
+		} else {
+			label = inputInactiveStyle.Render(label)
+		}
+		form.WriteString(label + value + "\n\n")
+	}
+
+	form.WriteString(inactiveStyle.Render("Times as HH:MM or YYYY-MM-DD HH:MM; project by name or ID"))
+	form.WriteString("\n")
+	form.WriteString(inactiveStyle.Render("Alt+Enter starts a new line of the note"))
+	form.WriteString("\n\n")
+	if m.Err != nil {
+		form.WriteString(errorStyle.Render(m.Err.Error()))
+		form.WriteString("\n\n")
+	}
+	form.WriteString(helpStyle.Render("Tab: Switch | Enter: Next/Save | Esc: Cancel"))
+
+	return lipgloss.Place(
+		80, 24,
+		lipgloss.Center, lipgloss.Center,
+		boxStyle.Width(60).Render(form.String()),
+	)
+}
+
 func (m *Model) formatAllLogsRow(lp project.LogWithProject, highlighted bool) string {
 	projName := lp.ProjectName
-	if len(projName) > 14 {
-		projName = projName[:13] + "…"
+	if r := []rune(projName); len(r) > 14 {
+		projName = string(r[:13]) + "…"
 	}
 
 	dateStr := lp.Log.StoppedAt.Format("Jan 02 15:04")
 	durStr := formatDuration(lp.Log.Duration)
+	if lp.Log.Overtime > 0 {
+		durStr += " " + overtimeStyle.Render(formatOvertime(lp.Log.Overtime))
+	}
 
 	tag := ""
-	if lp.Log.Tag != "" {
-		tag = lp.Log.Tag
-		if len(tag) > 20 {
-			tag = tag[:19] + "…"
+	if len(lp.Log.Tags) > 0 {
+		tag = timelog.FormatTags(lp.Log.Tags)
+		if r := []rune(tag); len(r) > 20 {
+			tag = string(r[:19]) + "…"
 		}
 	}
+	// The first line of the note fills what room the tag leaves.
+	note, _, _ := strings.Cut(lp.Log.Note, "\n")
+	if room := 28 - utf8.RuneCountInString(tag); note != "" && room > 4 {
+		if tag != "" {
+			room--
+		}
+		if r := []rune(note); len(r) > room {
+			note = string(r[:room-1]) + "…"
+		}
+	} else {
+		note = ""
+	}
 
 	row := fmt.Sprintf("  %-16s %-14s %-10s %s",
 		logProjectStyle.Render(projName),
@@ -434,6 +660,12 @@ func (m *Model) formatAllLogsRow(lp project.LogWithProject, highlighted bool) st
 		durStr,
 		logTagStyle.Render(tag),
 	)
+	if note != "" {
+		if tag != "" {
+			row += " "
+		}
+		row += inactiveStyle.Render(note)
+	}
 
 	if highlighted {
 		return logRowSelectedStyle.Render(row)
@@ -447,4 +679,9 @@ var (
 	runningStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("82")).
 			Bold(true)
+	errorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196"))
+	overtimeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("208")).
+			Bold(true)
 )
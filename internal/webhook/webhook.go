@@ -0,0 +1,269 @@
+// Package webhook POSTs timer events to configured URLs. Events are queued
+// in the database's outbox first, so deliveries survive restarts and are
+// retried with exponential backoff while an endpoint is down.
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"timer_tui/internal/hooks"
+	"timer_tui/internal/project"
+)
+
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body,
+	// keyed with the configured secret.
+	SignatureHeader = "X-Timer-TUI-Signature"
+	EventHeader     = "X-Timer-TUI-Event"
+	// DeliveryHeader is the outbox ID, the same on every retry so receivers
+	// can drop duplicates.
+	DeliveryHeader = "X-Timer-TUI-Delivery"
+)
+
+const (
+	firstRetry   = 10 * time.Second
+	maxRetry     = time.Hour
+	pollInterval = 15 * time.Second
+)
+
+// Config lists where to send events and the secret to sign them with.
+type Config struct {
+	URLs   []string
+	Secret string
+}
+
+// LoadConfig reads "url = ..." lines, any number of them, and an optional
+// "secret = ..." from path. Blank lines and lines starting with # are
+// ignored. A missing file or one without URLs yields nil.
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cfg Config
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case !ok || value == "":
+			return nil, fmt.Errorf("%s:%d: want \"url = ...\" or \"secret = ...\"", path, n)
+		case key == "url":
+			if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+				return nil, fmt.Errorf("%s:%d: url must be http or https", path, n)
+			}
+			cfg.URLs = append(cfg.URLs, value)
+		case key == "secret":
+			cfg.Secret = value
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", path, n, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cfg.URLs) == 0 {
+		return nil, nil
+	}
+	return &cfg, nil
+}
+
+// Emitter queues events in the store's outbox and delivers them. A nil
+// *Emitter does nothing, so callers need not check whether webhooks are
+// configured.
+type Emitter struct {
+	store  project.Store
+	cfg    Config
+	client *http.Client
+	wake   chan struct{}
+	mu     sync.Mutex // serializes Flush
+
+	// Errors, if set, receives a line for each failed delivery attempt.
+	Errors io.Writer
+}
+
+// New returns an emitter for cfg that queues through store. A nil client
+// uses one with a 10 second timeout.
+func New(store project.Store, cfg Config, client *http.Client) *Emitter {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Emitter{store: store, cfg: cfg, client: client, wake: make(chan struct{}, 1)}
+}
+
+// Enqueue adds a delivery of a start, stop or log event for every URL to the
+// outbox through tx, so it is stored with the change it describes or not at
+// all. Other events are not sent to webhooks. Call Wake once tx commits.
+func (e *Emitter) Enqueue(tx project.Store, p hooks.Payload) error {
+	if e == nil {
+		return nil
+	}
+	switch p.Event {
+	case hooks.Start, hooks.Stop, hooks.Log:
+	default:
+		return nil
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, url := range e.cfg.URLs {
+		msg := project.OutboxMessage{
+			URL:           url,
+			Event:         string(p.Event),
+			Payload:       body,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		if err := tx.EnqueueOutbox(&msg); err != nil {
+			return fmt.Errorf("queue %s event: %w", p.Event, err)
+		}
+	}
+	return nil
+}
+
+// Wake has Run attempt the deliveries queued so far straight away.
+func (e *Emitter) Wake() {
+	if e == nil {
+		return
+	}
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Due reports whether any delivery is due by now.
+func (e *Emitter) Due(now time.Time) (bool, error) {
+	if e == nil {
+		return false, nil
+	}
+	due, err := e.store.GetOutbox(now)
+	return len(due) > 0, err
+}
+
+// Flush attempts every delivery due by now once. Delivered messages leave
+// the outbox; failed ones are rescheduled.
+func (e *Emitter) Flush(now time.Time) error {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	due, err := e.store.GetOutbox(now)
+	if err != nil {
+		return err
+	}
+	for _, msg := range due {
+		if err := e.deliver(msg); err != nil {
+			msg.Attempts++
+			msg.NextAttemptAt = now.Add(Backoff(msg.Attempts))
+			msg.LastError = err.Error()
+			e.report(fmt.Errorf("deliver %s to %s (attempt %d): %w", msg.Event, msg.URL, msg.Attempts, err))
+			if err := e.store.UpdateOutbox(&msg); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.store.DeleteOutbox(msg.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run delivers queued events until ctx is done, as soon as they are emitted
+// and periodically for retries.
+func (e *Emitter) Run(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		if err := e.Flush(time.Now()); err != nil {
+			e.report(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Emitter) deliver(msg project.OutboxMessage) error {
+	req, err := http.NewRequest(http.MethodPost, msg.URL, bytes.NewReader(msg.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "timer_tui")
+	req.Header.Set(EventHeader, msg.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(msg.ID, 10))
+	if e.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(e.cfg.Secret, msg.Payload))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (e *Emitter) report(err error) {
+	if e.Errors != nil {
+		fmt.Fprintf(e.Errors, "webhook: %v\n", err)
+	}
+}
+
+// Sign returns the signature header value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns how long to wait before the next attempt after the given
+// number of failed ones: 10s, doubling each time, at most an hour.
+func Backoff(attempts int) time.Duration {
+	d := firstRetry
+	for i := 1; i < attempts && d < maxRetry; i++ {
+		d *= 2
+	}
+	return min(d, maxRetry)
+}
@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"timer_tui/internal/hooks"
+	"timer_tui/internal/project"
+)
+
+// queue enqueues an event the way the TUI and commands do, inside a
+// transaction.
+func queue(t *testing.T, store project.Store, e *Emitter, p hooks.Payload) {
+	t.Helper()
+	err := store.WithTx(func(tx project.Store) error {
+		return e.Enqueue(tx, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A delivery is signed with the secret and leaves the outbox once the
+// endpoint accepts it.
+func TestFlushDeliversSignedAndDeletes(t *testing.T) {
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	store := project.NewMemoryStore()
+	e := New(store, Config{URLs: []string{srv.URL}, Secret: "s3cret"}, srv.Client())
+	queue(t, store, e, hooks.Payload{Event: hooks.Start, Project: "Write"})
+
+	if err := e.Flush(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := header.Get(SignatureHeader), Sign("s3cret", body); got != want || got == "" {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := header.Get(EventHeader); got != "start" {
+		t.Errorf("event header = %q, want start", got)
+	}
+	if header.Get(DeliveryHeader) == "" {
+		t.Error("no delivery ID")
+	}
+	left, err := store.GetOutbox(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 0 {
+		t.Errorf("outbox = %+v, want empty after delivery", left)
+	}
+}
+
+// A failed delivery stays queued and is retried after the backoff, not
+// before.
+func TestFlushBacksOffOnFailure(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	store := project.NewMemoryStore()
+	e := New(store, Config{URLs: []string{srv.URL}}, srv.Client())
+	queue(t, store, e, hooks.Payload{Event: hooks.Stop, Project: "Write"})
+	queue(t, store, e, hooks.Payload{Event: hooks.Reset, Project: "Write"})
+
+	now := time.Now()
+	if err := e.Flush(now); err != nil {
+		t.Fatal(err)
+	}
+	left, err := store.GetOutbox(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].Attempts != 1 || !left[0].NextAttemptAt.Equal(now.Add(Backoff(1))) || left[0].LastError == "" {
+		t.Fatalf("outbox = %+v, want the stop rescheduled %s later", left, Backoff(1))
+	}
+
+	if err := e.Flush(now.Add(Backoff(1) - time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d before the backoff ran out, want 1", attempts)
+	}
+
+	status = http.StatusOK
+	if err := e.Flush(now.Add(Backoff(1))); err != nil {
+		t.Fatal(err)
+	}
+	if left, err = store.GetOutbox(time.Time{}); err != nil || len(left) != 0 || attempts != 2 {
+		t.Fatalf("after retry: outbox = %+v, %v, attempts %d; want delivered on the second", left, err, attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		4:  80 * time.Second,
+		9:  2560 * time.Second,
+		10: time.Hour,
+		50: time.Hour,
+	} {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
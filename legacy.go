@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"timer_tui/internal/config"
+)
+
+// legacyDeclinedMarker records that the user turned down moving a database
+// found in the working directory, so the offer is only made once.
+const legacyDeclinedMarker = ".legacy_db_declined"
+
+// offerLegacyMove asks whether to move a timer_tui.db left in the working
+// directory by older versions to target. It only asks on an interactive
+// terminal, and never when target already exists.
+func offerLegacyMove(target string) error {
+	legacy, err := filepath.Abs(config.DBFileName)
+	if err != nil {
+		return nil
+	}
+	if legacy == target {
+		return nil
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+	marker := filepath.Join(filepath.Dir(target), legacyDeclinedMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	}
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+
+	fmt.Printf("Found %s in the current directory.\nMove it to %s? [y/N] ", config.DBFileName, target)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		if err := moveDB(legacy, target); err != nil {
+			return fmt.Errorf("failed to move database: %w", err)
+		}
+		fmt.Printf("Moved database to %s\n", target)
+	default:
+		if err := os.WriteFile(marker, nil, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Leaving it in place. Run with --db %s to use it.\n", config.DBFileName)
+	}
+	return nil
+}
+
+// moveDB moves a SQLite database along with any journal files beside it.
+func moveDB(from, to string) error {
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		if _, err := os.Stat(from + suffix); err != nil {
+			continue
+		}
+		if err := moveFile(from+suffix, to+suffix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveFile renames a file, falling back to copy and delete when the rename
+// crosses filesystems.
+func moveFile(from, to string) error {
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(to)
+		return err
+	}
+	src.Close()
+	return os.Remove(from)
+}
@@ -1,24 +1,144 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/charmbracelet/bubbletea"
 	"timer_tui/internal"
+	"timer_tui/internal/config"
+	"timer_tui/internal/control"
+	"timer_tui/internal/project"
 )
 
 func main() {
-	m, err := internal.NewModel()
+	dbFlag := flag.String("db", "", "path to the SQLite database (default $XDG_DATA_HOME/timer_tui/timer_tui.db, or $"+config.DBEnvVar+")")
+	flag.Usage = usage
+	flag.Parse()
+
+	dbPath, isDefault, err := config.ResolveDBPath(*dbFlag)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	args := flag.Args()
+	// Prompts call this every second without a terminal to answer the
+	// legacy database question on.
+	if len(args) > 0 && args[0] == "prompt" {
+		if err := runPrompt(dbPath, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if isDefault {
+		if err := offerLegacyMove(dbPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(dbPath, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && !internal.IsCommand(args[0]) {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+
+	// A TUI running on the same database carries out what it can itself, so
+	// its in-memory state does not go stale.
+	socket := config.SocketPath(dbPath)
+	if len(args) > 0 {
+		handled, err := internal.RunRemote(socket, args[0], args[1:], os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if handled {
+			return
+		}
+	}
+
+	repo, err := project.NewRepository(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
+		os.Exit(1)
+	}
+
+	events, err := loadEvents(repo)
+	if err != nil {
+		repo.Close()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Deliveries still due on exit are left to a process of their own
+	// rather than holding the exit up; that process runs the webhooks
+	// command, which delivers in the foreground.
+	if len(args) == 0 || args[0] != "webhooks" {
+		events.Detach = detachWebhooks(dbPath)
+	}
+
+	if len(args) > 0 {
+		// Without a TUI to draw over, failing hooks and deliveries are
+		// reported.
+		if events.Hooks != nil {
+			events.Hooks.Errors = os.Stderr
+		}
+		if events.Webhooks != nil {
+			events.Webhooks.Errors = os.Stderr
+		}
+		err := internal.RunCommand(repo, events, args[0], args[1:], os.Stdout)
+		repo.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	m, err := internal.NewModel(repo)
+	if err != nil {
+		repo.Close()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	m.SetEvents(events)
+
+	idleCfg, err := loadIdle()
+	if err != nil {
+		m.Close()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	m.SetIdle(idleCfg)
 	defer m.Close()
 
+	// Stopped before m.Close, which detaches the deliveries still due.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go events.Webhooks.Run(ctx)
+
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
+	if ln, err := control.Listen(socket); err != nil {
+		m.Err = fmt.Errorf("commands cannot reach this instance: %w", err)
+	} else {
+		defer ln.Close()
+		go ln.Serve(internal.ControlHandler(p))
+	}
+
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
@@ -36,3 +156,31 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: timer_tui [--db PATH] [command]
+
+Without a command, the interactive TUI is started.
+
+Commands:
+  start <project> [--tag TAG]   start a timer, stopping any other
+  stop [--tag TAG]              stop the running timer and log it
+  tag <tag>                     set the tag the running session is logged with
+  add <project> --from HH:MM --to HH:MM [--tag TAG] [--note TEXT]
+                                log a session that was not timed
+  status                        show the running timer
+  list                          list projects
+  log [--project NAME] [--search TEXT] [-n N]
+                                show recent time logs and their notes
+  report                        show logged time per project, in budget vs overtime
+  tags [rename OLD NEW | merge TAG... --into TAG]
+                                show logged time per tag, or rename and merge tags
+  webhooks                      retry due webhook deliveries and list pending ones
+  serve [--addr HOST:PORT]      serve the JSON API (default 127.0.0.1:7777)
+  prompt [--format TEMPLATE]    print the running timer for a shell prompt or status bar
+  migrate status|up|down        manage database schema migrations
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"timer_tui/internal/project"
+)
+
+const migrateUsage = "usage: timer_tui migrate status|up|down"
+
+func runMigrate(dbPath string, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	repo, err := project.OpenRepository(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer repo.Close()
+
+	switch args[0] {
+	case "status":
+		statuses, err := repo.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%04d  %-32s %s\n", s.Version, s.Name, state)
+		}
+	case "up":
+		before, err := repo.SchemaVersion()
+		if err != nil {
+			return err
+		}
+		backup, err := repo.MigrateUp()
+		reportBackup(backup)
+		if err != nil {
+			return err
+		}
+		after, err := repo.SchemaVersion()
+		if err != nil {
+			return err
+		}
+		if after == before {
+			fmt.Printf("Already at version %d\n", after)
+		} else {
+			fmt.Printf("Migrated from version %d to %d\n", before, after)
+		}
+	case "down":
+		before, err := repo.SchemaVersion()
+		if err != nil {
+			return err
+		}
+		backup, err := repo.MigrateDown()
+		reportBackup(backup)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reverted version %d\n", before)
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
+
+func reportBackup(path string) {
+	if path != "" {
+		fmt.Printf("Backed up database to %s\n", path)
+	}
+}
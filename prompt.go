@@ -0,0 +1,26 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"time"
+
+	"timer_tui/internal/config"
+	"timer_tui/internal/prompt"
+)
+
+// runPrompt prints the running timer for a shell prompt or status bar. It
+// runs before anything else so a call every second stays cheap: the
+// database is neither migrated nor opened for writing.
+func runPrompt(dbPath string, args []string) error {
+	fs := flag.NewFlagSet("prompt", flag.ContinueOnError)
+	format := fs.String("format", prompt.DefaultFormat, "Go text/template for the output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("usage: timer_tui prompt [--format TEMPLATE]")
+	}
+	return prompt.Render(os.Stdout, dbPath, config.PromptCachePath(dbPath), *format, time.Now())
+}